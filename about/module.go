@@ -1,8 +1,8 @@
 package about
 
 import (
-	"embed"
 	"fmt"
+	"io/fs"
 	"net/http"
 
 	"github.com/haschi/dinge/webx"
@@ -33,12 +33,14 @@ func (res Module) GetUsage(w http.ResponseWriter, r *http.Request) {
 }
 
 type Module struct {
-	Templates embed.FS
+	Templates fs.FS
 }
 
 func (m *Module) Mount(mux *http.ServeMux, prefix string, middleware ...webx.Middleware) {
-	mux.Handle(fmt.Sprintf("GET %v/license", prefix), webx.CombineFunc(m.GetLicense, middleware...))
-	mux.Handle(fmt.Sprintf("GET %v/usage", prefix), webx.CombineFunc(m.GetUsage, middleware...))
+	viewer := webx.Authorize(webx.RoleViewer, middleware...)
+
+	mux.Handle(fmt.Sprintf("GET %v/license", prefix), webx.CombineFunc(m.GetLicense, viewer...))
+	mux.Handle(fmt.Sprintf("GET %v/usage", prefix), webx.CombineFunc(m.GetUsage, viewer...))
 }
 
 func (m *Module) Close() error {
@@ -0,0 +1,80 @@
+package barcode
+
+import (
+	"errors"
+	"image"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// Barcode ist ein in einem Bild erkannter Produktcode.
+//
+// Format verwendet die Bezeichnungen der Barcode Detection API des Browsers, zum Beispiel "ean_13" oder "qr_code".
+type Barcode struct {
+	Code   string `json:"code"`
+	Format string `json:"format"`
+}
+
+var formats = map[gozxing.BarcodeFormat]string{
+	gozxing.BarcodeFormat_EAN_13:   "ean_13",
+	gozxing.BarcodeFormat_EAN_8:    "ean_8",
+	gozxing.BarcodeFormat_UPC_A:    "upc_a",
+	gozxing.BarcodeFormat_UPC_E:    "upc_e",
+	gozxing.BarcodeFormat_CODE_128: "code_128",
+	gozxing.BarcodeFormat_QR_CODE:  "qr_code",
+}
+
+var possibleFormats = []gozxing.BarcodeFormat{
+	gozxing.BarcodeFormat_EAN_13,
+	gozxing.BarcodeFormat_EAN_8,
+	gozxing.BarcodeFormat_UPC_A,
+	gozxing.BarcodeFormat_UPC_E,
+	gozxing.BarcodeFormat_CODE_128,
+	gozxing.BarcodeFormat_QR_CODE,
+}
+
+// Decode sucht in einem Bild nach einem Barcode.
+//
+// Erkannt werden EAN-13, EAN-8, UPC-A, UPC-E, Code 128 und QR Codes. Enthält das Bild keinen erkennbaren Barcode, liefert Decode [ErrNoBarcode].
+func Decode(img image.Image) (Barcode, error) {
+	if img == nil {
+		return Barcode{}, ErrInvalidParameter
+	}
+
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return Barcode{}, err
+	}
+
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER:       true,
+		gozxing.DecodeHintType_POSSIBLE_FORMATS: possibleFormats,
+	}
+
+	readers := []gozxing.Reader{
+		oned.NewMultiFormatUPCEANReader(hints),
+		oned.NewCode128Reader(),
+		qrcode.NewQRCodeReader(),
+	}
+
+	for _, reader := range readers {
+		result, err := reader.Decode(bitmap, hints)
+		if err != nil {
+			continue
+		}
+
+		format, ok := formats[result.GetBarcodeFormat()]
+		if !ok {
+			continue
+		}
+
+		return Barcode{Code: result.GetText(), Format: format}, nil
+	}
+
+	return Barcode{}, ErrNoBarcode
+}
+
+var ErrNoBarcode = errors.New("no barcode found")
+var ErrInvalidParameter = errors.New("invalid paramater")
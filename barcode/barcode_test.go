@@ -0,0 +1,81 @@
+package barcode_test
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/haschi/dinge/barcode"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		img     image.Image
+		want    barcode.Barcode
+		wantErr error
+	}{
+		{
+			name: "ean 13",
+			img:  must(oned.NewEAN13Writer().Encode("4006381333931", gozxing.BarcodeFormat_EAN_13, 300, 120, nil)),
+			want: barcode.Barcode{Code: "4006381333931", Format: "ean_13"},
+		},
+		{
+			name: "ean 8",
+			img:  must(oned.NewEAN8Writer().Encode("96385074", gozxing.BarcodeFormat_EAN_8, 240, 120, nil)),
+			want: barcode.Barcode{Code: "96385074", Format: "ean_8"},
+		},
+		{
+			name: "upc a",
+			img:  must(oned.NewUPCAWriter().Encode("036000291452", gozxing.BarcodeFormat_UPC_A, 300, 120, nil)),
+			want: barcode.Barcode{Code: "036000291452", Format: "upc_a"},
+		},
+		{
+			name: "code 128",
+			img:  must(oned.NewCode128Writer().Encode("Schrauben M4", gozxing.BarcodeFormat_CODE_128, 400, 120, nil)),
+			want: barcode.Barcode{Code: "Schrauben M4", Format: "code_128"},
+		},
+		{
+			name: "qr code",
+			img:  must(qrcode.NewQRCodeWriter().Encode("https://example.com/dinge/42", gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)),
+			want: barcode.Barcode{Code: "https://example.com/dinge/42", Format: "qr_code"},
+		},
+		{
+			name:    "no barcode",
+			img:     image.NewGray(image.Rect(0, 0, 200, 200)),
+			wantErr: barcode.ErrNoBarcode,
+		},
+		{
+			name:    "no image",
+			img:     nil,
+			wantErr: barcode.ErrInvalidParameter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := barcode.Decode(tt.img)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v; want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Decode() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// must stoppt die Ausführung, wenn err nicht nil ist. Ansonsten liefert die Funktion den Wert value zurück.
+//
+// Die Funktion sollte nur in Testcode verwendet werden.
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+
+	return value
+}
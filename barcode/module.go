@@ -0,0 +1,64 @@
+package barcode
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/haschi/dinge/webx"
+)
+
+type Module struct{}
+
+func (m *Module) Mount(mux *http.ServeMux, prefix string, middleware ...webx.Middleware) {
+	viewer := webx.Authorize(webx.RoleViewer, middleware...)
+
+	mux.Handle(fmt.Sprintf("POST %v/", prefix), webx.CombineFunc(m.Decode, viewer...))
+}
+
+// ErrorData ist die Antwort, wenn kein Barcode erkannt wurde.
+type ErrorData struct {
+	Error string `json:"error"`
+}
+
+const Megabyte = 20
+
+// Decode erkennt einen Barcode in einem hochgeladenen Photo.
+//
+// Das Photo wird wie beim Hochladen von Photos im Feld file einer multipart/form-data Anfrage übertragen. Die Antwort ist ein JSON Objekt mit dem erkannten Code und dessen Format. Enthält das Photo keinen erkennbaren Barcode, antwortet Decode mit 422 Unprocessable Entity.
+func (m Module) Decode(w http.ResponseWriter, r *http.Request) {
+
+	img, err := webx.ImageFromForm(r, "file", 1<<Megabyte)
+	if err != nil {
+		response := webx.JsonResponse[ErrorData]{
+			Data:       ErrorData{Error: "Fehlerhafte Bilddatei"},
+			StatusCode: http.StatusUnprocessableEntity,
+		}
+
+		if err := response.Render(w); err != nil {
+			webx.ServerError(w, err)
+		}
+		return
+	}
+
+	barcode, err := Decode(img)
+	if err != nil {
+		response := webx.JsonResponse[ErrorData]{
+			Data:       ErrorData{Error: "Kein Produktcode erkannt"},
+			StatusCode: http.StatusUnprocessableEntity,
+		}
+
+		if err := response.Render(w); err != nil {
+			webx.ServerError(w, err)
+		}
+		return
+	}
+
+	response := webx.JsonResponse[Barcode]{
+		Data:       barcode,
+		StatusCode: http.StatusOK,
+	}
+
+	if err := response.Render(w); err != nil {
+		webx.ServerError(w, err)
+	}
+}
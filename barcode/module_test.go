@@ -0,0 +1,84 @@
+package barcode_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"github.com/haschi/dinge/barcode"
+	"github.com/haschi/dinge/webx"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestModule_Decode(t *testing.T) {
+
+	var ean13 bytes.Buffer
+	img := must(oned.NewEAN13Writer().Encode("4006381333931", gozxing.BarcodeFormat_EAN_13, 300, 120, nil))
+	if err := png.Encode(&ean13, img); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		content        []byte
+		wantStatusCode int
+		want           barcode.Barcode
+	}{
+		{
+			name:           "photo with barcode",
+			content:        ean13.Bytes(),
+			wantStatusCode: http.StatusOK,
+			want:           barcode.Barcode{Code: "4006381333931", Format: "ean_13"},
+		},
+		{
+			name:           "malformed image",
+			content:        []byte("no image"),
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	config := webx.TestserverConfig{
+		Database:   webx.InMemoryDatabase(),
+		Module:     NewBarcodeTestModule(),
+		Middleware: []webx.Middleware{},
+	}
+
+	testserver := webx.NewTestserver(t, "/barcode", config)
+	defer testserver.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := testserver.Upload("/barcode/", "file", "barcode.png", tt.content)
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("POST /barcode/ = %v; want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if contentType := response.Header.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("POST /barcode/ Content-Type = %v; want application/json", contentType)
+			}
+
+			var got barcode.Barcode
+			if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("POST /barcode/ = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func NewBarcodeTestModule() webx.ModuleConstructor {
+	return func(db *sql.DB) (webx.Module, error) {
+		return &barcode.Module{}, nil
+	}
+}
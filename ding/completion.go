@@ -0,0 +1,65 @@
+package ding
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Completion ist ein Vorschlag zur Vervollständigung einer Eingabe.
+type Completion struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+// Complete liefert höchstens limit Dinge, deren Name oder Code mit den Begriffen der Eingabe prefix beginnt.
+//
+// Die Eingabe wird wie bei [ParseQuery] in Begriffe zerlegt. Die relevantesten Dinge stehen am Anfang. Dinge im Papierkorb werden nicht vorgeschlagen.
+func (r Repository) Complete(ctx context.Context, prefix string, limit int) ([]Completion, error) {
+	completions := []Completion{}
+
+	match := ParseQuery(prefix)
+	if match == "" {
+		return completions, nil
+	}
+
+	q := `
+	SELECT dinge.name, dinge.code
+	FROM fulltext
+	INNER JOIN dinge ON dinge.id = fulltext.rowid
+	WHERE fulltext MATCH :query AND dinge.archiviert IS NULL
+	ORDER BY bm25(fulltext), dinge.name
+	LIMIT :limit`
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return completions, err
+	}
+
+	defer tx.Rollback()
+
+	// Die Begriffe werden nur im Namen und im Code gesucht.
+	rows, err := tx.QueryContext(q,
+		sql.Named("query", "{code name} : ("+match+")"),
+		sql.Named("limit", max(limit, 0)))
+
+	if err != nil {
+		return completions, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var completion Completion
+		if err := rows.Scan(&completion.Name, &completion.Code); err != nil {
+			return completions, err
+		}
+
+		completions = append(completions, completion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return completions, err
+	}
+
+	return completions, tx.Commit()
+}
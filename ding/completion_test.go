@@ -0,0 +1,48 @@
+package ding_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+)
+
+func TestRepository_Complete(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []ding.Completion
+	}{
+		{name: "empty", prefix: "", limit: 8, want: []ding.Completion{}},
+		{name: "name prefix", prefix: "pap", limit: 8, want: []ding.Completion{{Name: "Paprika", Code: "111"}}},
+		{name: "code prefix", prefix: "22", limit: 8, want: []ding.Completion{{Name: "Gurke", Code: "222"}}},
+		{name: "description is ignored", prefix: "nachtschatten", limit: 8, want: []ding.Completion{}},
+		{name: "archived", prefix: "tom", limit: 8, want: []ding.Completion{}},
+		{name: "limit", prefix: "g", limit: 0, want: []ding.Completion{}},
+	}
+
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		r := ding.Repository{Clock: FixedClock{}, Tm: tm}
+
+		if err := r.Archivieren(ctx, 3); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := r.Complete(ctx, tt.prefix, tt.limit)
+				if err != nil {
+					t.Fatalf("Repository.Complete(%q) error = %v", tt.prefix, err)
+				}
+
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Repository.Complete(%q) = %v; want %v", tt.prefix, got, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -5,19 +5,31 @@ CREATE TABLE dinge(
   anzahl INTEGER NOT NULL,
   beschreibung TEXT NOT NULL,
   allgemein TEXT NOT NULL,
-  aktualisiert DATETIME NOT NULL
+  aktualisiert DATETIME NOT NULL,
+  archiviert DATETIME
 );
 CREATE INDEX idx_dinge_aktualisiert ON dinge(aktualisiert);
 CREATE UNIQUE INDEX idx_dinge_code ON dinge(code);
-CREATE VIRTUAL TABLE fulltext USING fts5(code, name, allgemein, beschreibung);
+-- Die Suche unterscheidet nicht zwischen Umlauten und Vokalen: "schlussel" findet "Schlüssel".
+CREATE VIRTUAL TABLE fulltext USING fts5(
+  code,
+  name,
+  allgemein,
+  beschreibung,
+  tokenize = "unicode61 remove_diacritics 2"
+);
+-- Alle Wörter der Volltextsuche. Siehe Repository.Suggest
+CREATE VIRTUAL TABLE fulltext_vocab USING fts5vocab(fulltext, row);
 CREATE TABLE history(
   operation INTEGER NOT NULL REFERENCES operation,
   count INTEGER NOT NULL,
   created DATETIME NOT NULL,
-  dinge_id INTEGER NOT NULL REFERENCES dinge
+  dinge_id INTEGER NOT NULL REFERENCES dinge,
+  actor VARCHAR(100) NOT NULL DEFAULT ''
 );
 CREATE INDEX idx_history_created ON history(created);
 CREATE INDEX idx_history_dingeId ON history(dinge_id);
+CREATE INDEX idx_history_actor ON history(actor);
 CREATE TABLE operation(
   id INTEGER NOT NULL PRIMARY KEY,
   name TEXT NOT NULL
@@ -25,4 +37,26 @@ CREATE TABLE operation(
 INSERT INTO operation(id, name)
 VALUES(1, 'new'),
   (2, "add"),
-  (3, "delete");
+  (3, "delete"),
+  (4, "merge"),
+  (5, "archive"),
+  (6, "restore");
+CREATE TABLE aliases(
+  code VARCHAR(100) NOT NULL PRIMARY KEY,
+  dinge_id INTEGER NOT NULL REFERENCES dinge
+);
+CREATE INDEX idx_aliases_dingeId ON aliases(dinge_id);
+-- Gespeicherte Suchen mit allen Parametern der Übersicht. Siehe Repository.SaveSearch
+CREATE TABLE saved_searches(
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(100) NOT NULL,
+  q VARCHAR(100) NOT NULL,
+  s VARCHAR(20) NOT NULL,
+  leer BOOLEAN NOT NULL,
+  unter INTEGER NOT NULL,
+  seit DATETIME,
+  bis DATETIME,
+  photo VARCHAR(10) NOT NULL,
+  archiviert BOOLEAN NOT NULL
+);
+CREATE UNIQUE INDEX idx_saved_searches_name ON saved_searches(name);
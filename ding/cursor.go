@@ -0,0 +1,60 @@
+package ding
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor bezeichnet die Position in einem Suchergebnis, an der eine Seite beginnt.
+//
+// Der Cursor enthält den Sortierschlüssel und die Id des Dings, nach dem die Seite beginnt. Dadurch bleibt die Position stabil, auch wenn zwischenzeitlich Dinge hinzukommen oder entfernt werden. Der leere Cursor bezeichnet den Anfang des Suchergebnisses.
+type Cursor struct {
+	Sort string `json:"s,omitempty"`
+	Key  string `json:"k"`
+	Id   int64  `json:"i"`
+
+	// Backward ist wahr, wenn die Seite vor dem Ding Id endet, statt danach zu beginnen.
+	Backward bool `json:"b,omitempty"`
+}
+
+// IsZero ist wahr, wenn der Cursor den Anfang des Suchergebnisses bezeichnet.
+func (c Cursor) IsZero() bool {
+	return c.Id == 0
+}
+
+// String liefert den Cursor in einer Form, die als Parameter einer URL verwendet werden kann.
+//
+// Der leere Cursor wird als leere Zeichenkette dargestellt.
+func (c Cursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		// Ein Cursor besteht nur aus Zeichenketten, Zahlen und Wahrheitswerten.
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor liest einen Cursor, der mit [Cursor.String] erzeugt wurde.
+func ParseCursor(s string) (Cursor, error) {
+	var cursor Cursor
+	if s == "" {
+		return cursor, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("malformed cursor %q: %w", s, ErrInvalidParameter)
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id < 1 {
+		return Cursor{}, fmt.Errorf("malformed cursor %q: %w", s, ErrInvalidParameter)
+	}
+
+	return cursor, nil
+}
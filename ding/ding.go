@@ -1,12 +1,18 @@
 package ding
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 type Ding struct {
 	DingRef
 	Beschreibung string
 	Allgemein    string
 	Aktualisiert time.Time
+
+	// Archiviert ist der Zeitpunkt, zu dem das Ding in den Papierkorb gelegt wurde.
+	Archiviert sql.NullTime
 }
 
 // DingRef repräsentiert ein Ding in der Übersicht.
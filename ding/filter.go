@@ -0,0 +1,117 @@
+package ding
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Auswahl des Filters nach Photos.
+const (
+	MitPhoto  = "mit"
+	OhnePhoto = "ohne"
+)
+
+// PhotoOptions sind die zulässigen Werte von [Filter.Photo].
+var PhotoOptions = []string{"", MitPhoto, OhnePhoto}
+
+// Filter schränkt ein Suchergebnis ein. Der leere Filter liefert alle Dinge, die nicht im Papierkorb liegen.
+type Filter struct {
+	// Leer ist wahr, wenn nur Dinge mit der Anzahl 0 geliefert werden.
+	Leer bool
+
+	// Unter ist die Anzahl, die ein Ding unterschreiten muss. Unter 0 schränkt die Anzahl nicht ein.
+	Unter int
+
+	// Seit ist der Tag, an dem oder nach dem ein Ding zuletzt aktualisiert wurde.
+	Seit time.Time
+
+	// Bis ist der Tag, vor dem ein Ding zuletzt aktualisiert wurde.
+	Bis time.Time
+
+	// Photo ist [MitPhoto] oder [OhnePhoto], wenn nur Dinge mit oder ohne Photo geliefert werden.
+	Photo string
+
+	// Archiviert ist wahr, wenn statt der Dinge im Lager die Dinge im Papierkorb geliefert werden.
+	Archiviert bool
+}
+
+// dateLayout ist das Format der Tage eines Filters in URLs und Abfragen.
+const dateLayout = time.DateOnly
+
+// where übersetzt den Filter in eine Bedingung für die Tabelle dinge und ihre Parameter.
+func (f Filter) where() (string, []any, error) {
+	if !slices.Contains(PhotoOptions, f.Photo) {
+		return "", nil, fmt.Errorf("unknown photo filter %q: %w", f.Photo, ErrInvalidParameter)
+	}
+
+	conditions := []string{"dinge.archiviert IS NULL"}
+	if f.Archiviert {
+		conditions = []string{"dinge.archiviert IS NOT NULL"}
+	}
+
+	args := []any{}
+	if f.Leer {
+		conditions = append(conditions, "dinge.anzahl = 0")
+	}
+
+	if f.Unter > 0 {
+		conditions = append(conditions, "dinge.anzahl < :unter")
+		args = append(args, sql.Named("unter", f.Unter))
+	}
+
+	// aktualisiert beginnt mit dem Tag, so dass Tage als Zeichenketten verglichen werden können.
+	if !f.Seit.IsZero() {
+		conditions = append(conditions, "dinge.aktualisiert >= :seit")
+		args = append(args, sql.Named("seit", f.Seit.Format(dateLayout)))
+	}
+
+	if !f.Bis.IsZero() {
+		conditions = append(conditions, "dinge.aktualisiert < :bis")
+		args = append(args, sql.Named("bis", f.Bis.Format(dateLayout)))
+	}
+
+	// Die Tabelle photos gehört zum Paket photo. Sie wird nur abgefragt, wenn nach Photos gefiltert wird.
+	switch f.Photo {
+	case MitPhoto:
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM photos WHERE photos.dinge_id = dinge.id)")
+	case OhnePhoto:
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM photos WHERE photos.dinge_id = dinge.id)")
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// Values liefert die Parameter einer URL, die den Filter beschreiben. Nicht gesetzte Filter sind nicht enthalten.
+func (f Filter) Values() url.Values {
+	values := url.Values{}
+	if f.Leer {
+		values.Set("leer", "on")
+	}
+
+	if f.Unter > 0 {
+		values.Set("unter", strconv.Itoa(f.Unter))
+	}
+
+	if !f.Seit.IsZero() {
+		values.Set("seit", f.Seit.Format(dateLayout))
+	}
+
+	if !f.Bis.IsZero() {
+		values.Set("bis", f.Bis.Format(dateLayout))
+	}
+
+	if f.Photo != "" {
+		values.Set("photo", f.Photo)
+	}
+
+	if f.Archiviert {
+		values.Set("archiviert", "on")
+	}
+
+	return values
+}
@@ -0,0 +1,80 @@
+package ding_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/photo"
+	"github.com/haschi/dinge/sqlx"
+)
+
+func TestRepository_Page_Filter(t *testing.T) {
+	// Nur die Gurke hat ein Photo. Die Tomate liegt im Papierkorb.
+	setup := func(db *sql.DB) error {
+		return sqlx.ExecuteScripts(db, ding.CreateScript, photo.CreateScript, ding.FixtureScript, `
+		INSERT INTO photos(photo, mime_type, hash, aktualisiert, dinge_id)
+		VALUES (X'0123456789', 'image/png', 'hash', '2024-11-13 19:05:02', 2);
+		UPDATE dinge SET archiviert = '2024-12-01 10:00:00', anzahl = 0 WHERE id = 3;
+		UPDATE dinge SET anzahl = 0 WHERE id = 1;`)
+	}
+
+	day := func(s string) time.Time {
+		return must(time.Parse(time.DateOnly, s))
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		filter ding.Filter
+		want   []int64
+	}{
+		{name: "no filter", filter: ding.Filter{}, want: []int64{2, 1}},
+		{name: "empty", filter: ding.Filter{Leer: true}, want: []int64{1}},
+		{name: "below", filter: ding.Filter{Unter: 2}, want: []int64{1}},
+		{name: "below all", filter: ding.Filter{Unter: 3}, want: []int64{2, 1}},
+		{name: "since", filter: ding.Filter{Seit: day("2024-11-14")}, want: []int64{}},
+		{name: "since same day", filter: ding.Filter{Seit: day("2024-11-13")}, want: []int64{2, 1}},
+		{name: "before", filter: ding.Filter{Bis: day("2024-11-13")}, want: []int64{}},
+		{name: "before next day", filter: ding.Filter{Bis: day("2024-11-14")}, want: []int64{2, 1}},
+		{name: "with photo", filter: ding.Filter{Photo: ding.MitPhoto}, want: []int64{2}},
+		{name: "without photo", filter: ding.Filter{Photo: ding.OhnePhoto}, want: []int64{1}},
+		{name: "archived", filter: ding.Filter{Archiviert: true}, want: []int64{3}},
+		{name: "archived and empty", filter: ding.Filter{Archiviert: true, Leer: true}, want: []int64{3}},
+		{name: "query and filter", query: "gurke", filter: ding.Filter{Photo: ding.OhnePhoto}, want: []int64{}},
+		{name: "query in trash", query: "tomate", filter: ding.Filter{Archiviert: true}, want: []int64{3}},
+	}
+
+	withTransactionManager(t, setup, func(t *testing.T, tm sqlx.TransactionManager) {
+		r := ding.Repository{Clock: FixedClock{}, Tm: tm}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				page, err := r.Page(context.Background(), 12, tt.query, tt.filter, "", ding.Cursor{})
+				if err != nil {
+					t.Fatalf("Repository.Page(%v) error = %v", tt.filter, err)
+				}
+
+				got := []int64{}
+				for _, ref := range page.Result {
+					got = append(got, ref.Id)
+				}
+
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Repository.Page(%v) = %v; want %v", tt.filter, got, tt.want)
+				}
+			})
+		}
+
+		t.Run("unknown photo filter", func(t *testing.T) {
+			_, err := r.Page(context.Background(), 12, "", ding.Filter{Photo: "vielleicht"}, "", ding.Cursor{})
+			if !errors.Is(err, ding.ErrInvalidParameter) {
+				t.Errorf("Repository.Page() error = %v; want %v", err, ding.ErrInvalidParameter)
+			}
+		})
+	})
+}
@@ -0,0 +1,197 @@
+package ding
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// minSimilarity ist die Ähnlichkeit, die ein Wort der Volltextsuche mindestens zu einem Suchbegriff haben muss, um ihn zu ersetzen.
+const minSimilarity = 0.3
+
+// resolveQuery übersetzt die Sucheingabe query in eine FTS5 Abfrage.
+//
+// Findet die Abfrage kein Ding, das dem Filter entspricht, wird stattdessen die Abfrage des Vorschlags von [Repository.Suggest] verwendet. Der Vorschlag ist leer, wenn die Sucheingabe verwendet wurde.
+func (r Repository) resolveQuery(ctx context.Context, query string, filter Filter) (match string, suggestion string, err error) {
+	match = ParseQuery(query)
+	if match == "" {
+		return match, "", nil
+	}
+
+	where, args, err := filter.where()
+	if err != nil {
+		return match, "", err
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return match, "", err
+	}
+
+	defer tx.Rollback()
+
+	exists := `
+	SELECT EXISTS (
+	  SELECT 1
+	  FROM fulltext
+	  INNER JOIN dinge ON dinge.id = fulltext.rowid
+	  WHERE fulltext MATCH :query AND ` + where + `
+	)`
+
+	var found bool
+	if err := tx.QueryRowContext(exists, append(args, sql.Named("query", match))...).Scan(&found); err != nil {
+		return match, "", err
+	}
+
+	if found {
+		return match, "", tx.Commit()
+	}
+
+	suggestion, err = r.Suggest(ctx, query)
+	if err != nil || suggestion == "" {
+		return match, "", err
+	}
+
+	return ParseQuery(suggestion), suggestion, tx.Commit()
+}
+
+// Suggest liefert einen Vorschlag für eine Sucheingabe, die vermutlich Tippfehler enthält.
+//
+// Jeder Suchbegriff, der kein Wort der Volltextsuche ist, wird durch das ähnlichste Wort der Volltextsuche ersetzt. Die Ähnlichkeit wird aus den gemeinsamen Trigrammen der Wörter bestimmt. Umlaute und Akzente werden dabei nicht berücksichtigt, "ue" gilt als "ü". Ist kein Begriff zu ersetzen, liefert Suggest eine leere Zeichenkette.
+func (r Repository) Suggest(ctx context.Context, query string) (string, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`SELECT term FROM fulltext_vocab`)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+
+	vocabulary := []word{}
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return "", err
+		}
+
+		folded := fold(term)
+		vocabulary = append(vocabulary, word{term: term, folded: folded, trigrams: trigrams(folded)})
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	changed := false
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		// Feldnamen und Anführungszeichen bleiben erhalten.
+		prefix := ""
+		if field, rest, found := strings.Cut(term, ":"); found {
+			prefix, term = field+":", rest
+		}
+
+		// Begriffe, die die Volltextsuche findet, werden nicht ersetzt.
+		text := strings.Trim(term, `"`)
+		plain := normalize(text)
+		if plain == "" || slices.ContainsFunc(vocabulary, func(w word) bool { return strings.HasPrefix(w.term, plain) }) {
+			continue
+		}
+
+		folded := fold(text)
+		grams := trigrams(folded)
+		best, bestSimilarity := "", minSimilarity
+		for _, w := range vocabulary {
+			similarity := jaccard(grams, w.trigrams)
+			if strings.HasPrefix(w.folded, folded) {
+				similarity = 1
+			}
+
+			if similarity > bestSimilarity || (similarity == bestSimilarity && best != "" && w.term < best) {
+				best, bestSimilarity = w.term, similarity
+			}
+		}
+
+		if best != "" {
+			terms[i] = prefix + strings.Replace(term, text, best, 1)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return "", tx.Commit()
+	}
+
+	return strings.Join(terms, " "), tx.Commit()
+}
+
+// word ist ein Wort der Volltextsuche.
+type word struct {
+	term     string
+	folded   string
+	trigrams map[string]bool
+}
+
+// umschreibung ersetzt die Umschreibungen der Umlaute.
+var umschreibung = strings.NewReplacer("ae", "a", "oe", "o", "ue", "u", "ß", "ss")
+
+// normalize liefert s in Kleinbuchstaben ohne Akzente und Satzzeichen, so wie die Volltextsuche Wörter speichert.
+func normalize(s string) string {
+	removeDiacritics := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	s = strings.ToLower(s)
+	if removed, _, err := transform.String(removeDiacritics, s); err == nil {
+		s = removed
+	}
+
+	return strings.Map(func(r rune) rune {
+		if isWordCharacter(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// fold liefert s wie [normalize], ersetzt aber zusätzlich die Umschreibungen der Umlaute, so dass "Schluessel" und "Schlüssel" gleich sind.
+func fold(s string) string {
+	return normalize(umschreibung.Replace(strings.ToLower(s)))
+}
+
+// trigrams liefert die Trigramme eines Wortes. Der Anfang und das Ende des Wortes werden mit Leerzeichen markiert.
+func trigrams(word string) map[string]bool {
+	grams := map[string]bool{}
+	padded := []rune("  " + word + " ")
+	for i := 0; i+3 <= len(padded); i++ {
+		grams[string(padded[i:i+3])] = true
+	}
+	return grams
+}
+
+// jaccard liefert den Anteil der gemeinsamen Trigramme an allen Trigrammen von a und b.
+func jaccard(a, b map[string]bool) float64 {
+	common := 0
+	for gram := range a {
+		if b[gram] {
+			common++
+		}
+	}
+
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+
+	return float64(common) / float64(union)
+}
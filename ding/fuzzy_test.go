@@ -0,0 +1,67 @@
+package ding_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+)
+
+func TestRepository_Page_Fuzzy(t *testing.T) {
+	tests := []struct {
+		query          string
+		want           []int64
+		wantSuggestion string
+	}{
+		{query: "Schlüssel", want: []int64{4}},
+		{query: "schlussel", want: []int64{4}},
+		{query: "Schluessel", want: []int64{4}, wantSuggestion: "schlussel"},
+		{query: "Papirka", want: []int64{1}, wantSuggestion: "paprika"},
+		{query: "name:tomte", want: []int64{3}, wantSuggestion: "name:tomate"},
+		{query: "gurke", want: []int64{2}},
+		{query: "xyz", want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				ctx := context.Background()
+				r := ding.Repository{Clock: system.RealClock{}, Tm: tm}
+
+				result, err := r.Insert(ctx, "444", 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := r.Aktualisieren(ctx, ding.Aktualisierungsanfrage{Id: result.Id, Name: "Schlüssel"}); err != nil {
+					t.Fatal(err)
+				}
+
+				page, err := r.Page(ctx, 12, tt.query, ding.Filter{}, "", ding.Cursor{})
+				if err != nil {
+					t.Fatalf("Repository.Page(%q) error = %v", tt.query, err)
+				}
+
+				got := []int64{}
+				for _, ref := range page.Result {
+					got = append(got, ref.Id)
+				}
+
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Repository.Page(%q) = %v; want %v", tt.query, got, tt.want)
+				}
+
+				if page.Suggestion != tt.wantSuggestion {
+					t.Errorf("Repository.Page(%q).Suggestion = %q; want %q", tt.query, page.Suggestion, tt.wantSuggestion)
+				}
+
+				if tm.Count() != 0 {
+					t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+				}
+			})
+		})
+	}
+}
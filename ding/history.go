@@ -7,12 +7,21 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/haschi/dinge/webx"
 	"github.com/mattn/go-sqlite3"
 )
 
+// LogEvent protokolliert die Operation operation am Ding dingId.
+//
+// Als Akteur wird der angemeldete Benutzer oder API Client aus dem Kontext ctx gespeichert, siehe [webx.Principal.Actor]. Ohne angemeldeten Benutzer bleibt der Akteur leer.
 func (r Repository) LogEvent(ctx context.Context, operation int, count int, dingId int64) error {
-	statement := `INSERT INTO history(operation, count, created, dinge_id)
-	VALUES(:operation, :count, :created, :dinge_id)`
+	statement := `INSERT INTO history(operation, count, created, dinge_id, actor)
+	VALUES(:operation, :count, :created, :dinge_id, :actor)`
+
+	var actor string
+	if principal, ok := webx.PrincipalFrom(ctx); ok {
+		actor = principal.Actor()
+	}
 
 	tx, err := r.Tm.BeginTx(ctx)
 	if err != nil {
@@ -26,6 +35,7 @@ func (r Repository) LogEvent(ctx context.Context, operation int, count int, ding
 		sql.Named("count", count),
 		sql.Named("created", r.Clock.Now()),
 		sql.Named("dinge_id", dingId),
+		sql.Named("actor", actor),
 	)
 
 	if err != nil {
@@ -57,7 +67,7 @@ func dataAccessError(err error) error {
 func (r Repository) ProductHistory(ctx context.Context, dingId int64, limit int) ([]Event, error) {
 
 	q := `
-	SELECT id, code, name, operation, count, created
+	SELECT id, code, name, operation, count, created, actor
 	FROM history
 	INNER JOIN dinge ON history.dinge_id = dinge.id
 	WHERE dinge.id = :id
@@ -91,7 +101,8 @@ func (r Repository) GetEvents(ctx context.Context, query string, args ...any) ([
 			&event.DingRef.Name,
 			&event.Operation,
 			&event.Anzahl,
-			&event.Created); err != nil {
+			&event.Created,
+			&event.Actor); err != nil {
 			return history, err
 		}
 
@@ -103,7 +114,7 @@ func (r Repository) GetEvents(ctx context.Context, query string, args ...any) ([
 
 func (r Repository) GetAllEvents(ctx context.Context, limit int) ([]Event, error) {
 	q := `
-		SELECT id, code, name, operation, count, created
+		SELECT id, code, name, operation, count, created, actor
 		FROM history
 		INNER JOIN dinge ON history.dinge_id = dinge.id
 		ORDER BY created DESC
@@ -113,10 +124,61 @@ func (r Repository) GetAllEvents(ctx context.Context, limit int) ([]Event, error
 	return r.GetEvents(ctx, q, sql.Named("limit", limit))
 }
 
+// ActorHistory liefert die letzten Ereignisse des Akteurs actor.
+func (r Repository) ActorHistory(ctx context.Context, actor string, limit int) ([]Event, error) {
+	q := `
+		SELECT id, code, name, operation, count, created, actor
+		FROM history
+		INNER JOIN dinge ON history.dinge_id = dinge.id
+		WHERE history.actor = :actor
+		ORDER BY created DESC
+		LIMIT :limit
+		`
+
+	return r.GetEvents(ctx, q, sql.Named("actor", actor), sql.Named("limit", limit))
+}
+
+// Actors liefert die Namen aller Akteure der Historie, sortiert nach Namen.
+func (r Repository) Actors(ctx context.Context) ([]string, error) {
+	actors := []string{}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return actors, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`SELECT DISTINCT actor FROM history WHERE actor <> '' ORDER BY actor`)
+	if err != nil {
+		return actors, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var actor string
+		if err := rows.Scan(&actor); err != nil {
+			return actors, err
+		}
+
+		actors = append(actors, actor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return actors, err
+	}
+
+	return actors, tx.Commit()
+}
+
 type Event struct {
 	Operation int
 	Anzahl    int
 	Created   time.Time
+
+	// Actor ist der Name des Benutzers oder API Clients, der die Operation ausgeführt hat.
+	Actor string
 	DingRef
 }
 
@@ -124,6 +186,7 @@ func (e Event) Equal(other Event) bool {
 	return e.Operation == other.Operation &&
 		e.Anzahl == other.Anzahl &&
 		e.Created.Equal(other.Created) &&
+		e.Actor == other.Actor &&
 		e.DingRef.Equal(other.DingRef)
 }
 
@@ -135,6 +198,12 @@ func (e Event) String() string {
 		return fmt.Sprintf("Eingelagert: %v Stück", e.Anzahl)
 	case 3:
 		return fmt.Sprintf("Entnommen: %v Stück", e.Anzahl)
+	case 4:
+		return fmt.Sprintf("Zusammengeführt: %v Stück", e.Anzahl)
+	case 5:
+		return "In den Papierkorb gelegt"
+	case 6:
+		return "Aus dem Papierkorb wiederhergestellt"
 	default:
 		return "Unbekannte Operation"
 	}
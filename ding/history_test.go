@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/haschi/dinge/sqlx"
 	"github.com/haschi/dinge/system"
 	"github.com/haschi/dinge/testx"
+	"github.com/haschi/dinge/webx"
 )
 
 func TestRepository_LogEvent(t *testing.T) {
@@ -252,6 +254,62 @@ func TestRepository_ProductHistory(t *testing.T) {
 	}
 }
 
+func TestRepository_ActorHistory(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *webx.Principal
+		actor      string
+		wantEvents int
+		wantActors []string
+	}{
+		{name: "logged in user", principal: &webx.Principal{Name: "anna"}, actor: "anna", wantEvents: 1, wantActors: []string{"anna"}},
+		{name: "api token", principal: &webx.Principal{Name: "anna", Client: "Scanner"}, actor: "anna (Scanner)", wantEvents: 1, wantActors: []string{"anna (Scanner)"}},
+		{name: "other user", principal: &webx.Principal{Name: "anna"}, actor: "bert", wantEvents: 0, wantActors: []string{"anna"}},
+		{name: "without user", actor: "", wantEvents: 4, wantActors: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				repository := &ding.Repository{Clock: system.RealClock{}, Tm: tm}
+
+				ctx := context.Background()
+				if tt.principal != nil {
+					ctx = webx.WithPrincipal(ctx, *tt.principal)
+				}
+
+				if err := repository.LogEvent(ctx, 2, 1, dinge[0].Id); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := repository.ActorHistory(context.Background(), tt.actor, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(got) != tt.wantEvents {
+					t.Errorf("Repository.ActorHistory() = %v, want %v events", got, tt.wantEvents)
+				}
+
+				for _, event := range got {
+					if event.Actor != tt.actor {
+						t.Errorf("Repository.ActorHistory() actor = %q, want %q", event.Actor, tt.actor)
+					}
+				}
+
+				actors, err := repository.Actors(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !slices.Equal(actors, tt.wantActors) {
+					t.Errorf("Repository.Actors() = %v, want %v", actors, tt.wantActors)
+				}
+			})
+		})
+	}
+}
+
 // CancelableTransactionManager ist ein [sqlx.TransactionManager], der seinen [context.Context] nach einer vorgegebenen Anzahl von Operationen abschließt (canceled).
 //
 // Dieser TransactionManager wird in Unit Tests verwendet, um Fehler zu simulieren.
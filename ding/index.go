@@ -1,7 +1,34 @@
 package ding
 
+import "github.com/haschi/dinge/webx"
+
 type IndexFormData struct {
 	Q      string
 	S      string
+	Filter Filter
 	Result []DingRef
+
+	// Name ist der Name, unter dem die Suche gespeichert werden soll.
+	Name string
+
+	// SavedSearches sind die gespeicherten Suchen, die als Schnellzugriff angeboten werden.
+	SavedSearches []SavedSearch
+
+	// Suggestion ist die Sucheingabe, deren Ergebnis angezeigt wird, weil Q kein Ding gefunden hat.
+	Suggestion string
+
+	// Highlights enthält die hervorgehobenen Fundstellen zu den Ids der Dinge in Result.
+	Highlights map[int64]Highlight
+
+	// Principal ist der angemeldete Benutzer. Nur Administratoren exportieren das Ergebnis einer gespeicherten Suche.
+	Principal webx.Principal
+
+	// Next und Previous sind die Cursor der benachbarten Seiten. Sie sind leer, wenn es keine benachbarte Seite gibt.
+	Next     string
+	Previous string
+}
+
+// Url liefert die Adresse der Übersicht mit der Sucheingabe q an der Position cursor. Sortierung und Filter bleiben erhalten.
+func (d IndexFormData) Url(q string, cursor string) string {
+	return searchUrl(q, d.S, d.Filter, cursor)
 }
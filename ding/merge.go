@@ -0,0 +1,220 @@
+package ding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/haschi/dinge/sqlx"
+)
+
+// MergeFunc wird beim Zusammenführen zweier Dinge innerhalb der Transaktion aufgerufen, bevor das Ding otherId entfernt wird.
+//
+// Mit MergeFunc übernehmen andere Module ihre Daten, zum Beispiel Photos, vom Ding otherId für das Ding survivorId.
+type MergeFunc func(ctx context.Context, survivorId int64, otherId int64) error
+
+// Merge führt das Ding otherId mit dem Ding survivorId zusammen.
+//
+// Liegt eines der Dinge im Papierkorb, liefert Merge [ErrInvalidParameter].
+//
+// Das Ding survivorId übernimmt die Anzahl, die Historie und die Codes des Dings otherId. Fehlen dem Ding survivorId Name, allgemeiner Name oder Beschreibung, werden diese vom Ding otherId übernommen. Der Code des Dings otherId bleibt als Alias erhalten, so dass ein späteres Scannen des Codes das Ding survivorId findet. Anschließend wird das Ding otherId entfernt. Alle Änderungen erfolgen in einer Transaktion, in der auch die participants aufgerufen werden.
+func (r Repository) Merge(ctx context.Context, survivorId int64, otherId int64, participants ...MergeFunc) error {
+	if ctx == nil {
+		return errors.New("no context provided")
+	}
+
+	if survivorId == otherId {
+		return fmt.Errorf("can not merge ding %v with itself: %w", survivorId, ErrInvalidParameter)
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	survivor, err := r.GetById(ctx, survivorId)
+	if err != nil {
+		return notFound(err)
+	}
+
+	other, err := r.GetById(ctx, otherId)
+	if err != nil {
+		return notFound(err)
+	}
+
+	// Würde ein Ding im Papierkorb später endgültig gelöscht, gingen die übernommene Anzahl und der Alias verloren.
+	if survivor.Archiviert.Valid || other.Archiviert.Valid {
+		return fmt.Errorf("can not merge ding %v with ding %v in trash: %w", survivorId, otherId, ErrInvalidParameter)
+	}
+
+	for _, participant := range participants {
+		if err := participant(ctx, survivorId, otherId); err != nil {
+			return err
+		}
+	}
+
+	statements := []struct {
+		statement string
+		args      []any
+	}{
+		{
+			statement: `
+			UPDATE dinge
+			SET anzahl = anzahl + :anzahl,
+			  name = CASE WHEN name = '' THEN :name ELSE name END,
+			  allgemein = CASE WHEN allgemein = '' THEN :allgemein ELSE allgemein END,
+			  beschreibung = CASE WHEN beschreibung = '' THEN :beschreibung ELSE beschreibung END,
+			  aktualisiert = :aktualisiert
+			WHERE id = :id`,
+			args: []any{
+				sql.Named("id", survivorId),
+				sql.Named("anzahl", other.Anzahl),
+				sql.Named("name", other.Name),
+				sql.Named("allgemein", other.Allgemein),
+				sql.Named("beschreibung", other.Beschreibung),
+				sql.Named("aktualisiert", r.Clock.Now()),
+			},
+		},
+		{
+			statement: `UPDATE history SET dinge_id = :survivor WHERE dinge_id = :other`,
+			args:      []any{sql.Named("survivor", survivorId), sql.Named("other", otherId)},
+		},
+		{
+			statement: `UPDATE aliases SET dinge_id = :survivor WHERE dinge_id = :other`,
+			args:      []any{sql.Named("survivor", survivorId), sql.Named("other", otherId)},
+		},
+		{
+			statement: `INSERT INTO aliases(code, dinge_id) VALUES(:code, :survivor)`,
+			args:      []any{sql.Named("code", other.Code), sql.Named("survivor", survivorId)},
+		},
+		{
+			// Der Alias bleibt über die Volltextsuche auffindbar.
+			statement: `
+			UPDATE fulltext
+			SET code = code || ' ' || (SELECT code FROM fulltext WHERE rowid = :other),
+			  name = CASE WHEN name = '' THEN :name ELSE name END,
+			  allgemein = CASE WHEN allgemein = '' THEN :allgemein ELSE allgemein END,
+			  beschreibung = CASE WHEN beschreibung = '' THEN :beschreibung ELSE beschreibung END
+			WHERE rowid = :survivor`,
+			args: []any{
+				sql.Named("survivor", survivorId),
+				sql.Named("other", otherId),
+				sql.Named("name", other.Name),
+				sql.Named("allgemein", other.Allgemein),
+				sql.Named("beschreibung", other.Beschreibung),
+			},
+		},
+		{
+			statement: `DELETE FROM fulltext WHERE rowid = :other`,
+			args:      []any{sql.Named("other", otherId)},
+		},
+		{
+			statement: `DELETE FROM dinge WHERE id = :other`,
+			args:      []any{sql.Named("other", otherId)},
+		},
+	}
+
+	for _, s := range statements {
+		if _, err := tx.ExecContext(s.statement, s.args...); err != nil {
+			return err
+		}
+	}
+
+	if err := r.LogEvent(ctx, 4, other.Anzahl, survivor.Id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByCode liefert das Ding mit dem Code code.
+//
+// Ist code der Alias eines zusammengeführten Dings, liefert GetByCode das Ding, in das es überführt wurde. Dinge im Papierkorb werden nicht gefunden.
+func (r Repository) GetByCode(ctx context.Context, code string) (Ding, error) {
+	if ctx == nil {
+		return Ding{}, errors.New("no context provided")
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return Ding{}, err
+	}
+
+	defer tx.Rollback()
+
+	suchen := `SELECT id FROM dinge WHERE code = :code AND archiviert IS NULL`
+
+	var id int64
+	row := tx.QueryRowContext(suchen, sql.Named("code", canonicalCode(tx, code)))
+	if err := row.Scan(&id); err != nil {
+		return Ding{}, err
+	}
+
+	ding, err := r.GetById(ctx, id)
+	if err != nil {
+		return ding, err
+	}
+
+	return ding, tx.Commit()
+}
+
+// Aliases liefert die Codes der Dinge, die mit dem Ding dingId zusammengeführt wurden.
+func (r Repository) Aliases(ctx context.Context, dingId int64) ([]string, error) {
+	aliases := []string{}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return aliases, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`SELECT code FROM aliases WHERE dinge_id = :id ORDER BY code`, sql.Named("id", dingId))
+	if err != nil {
+		return aliases, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return aliases, err
+		}
+		aliases = append(aliases, alias)
+	}
+
+	if err := rows.Err(); err != nil {
+		return aliases, err
+	}
+
+	return aliases, tx.Commit()
+}
+
+// canonicalCode liefert den Code des Dings, in das ein Ding mit dem Code code überführt wurde.
+//
+// Ist code kein Alias, liefert canonicalCode den Code unverändert zurück.
+func canonicalCode(tx sqlx.Transaction, code string) string {
+	suchen := `
+	SELECT dinge.code
+	FROM aliases
+	INNER JOIN dinge ON aliases.dinge_id = dinge.id
+	WHERE aliases.code = :code`
+
+	var canonical string
+	if err := tx.QueryRowContext(suchen, sql.Named("code", code)).Scan(&canonical); err != nil {
+		return code
+	}
+
+	return canonical
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoRecord
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package ding_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+)
+
+func TestRepository_Merge(t *testing.T) {
+	timestamp := must(time.Parse(time.DateTime, "2024-12-01 10:00:00"))
+
+	tests := []struct {
+		name         string
+		survivorId   int64
+		otherId      int64
+		participants []ding.MergeFunc
+		wantErr      error
+	}{
+		{name: "merge gurke into paprika", survivorId: 1, otherId: 2},
+		{name: "merge with itself", survivorId: 1, otherId: 1, wantErr: ding.ErrInvalidParameter},
+		{name: "merge unknown survivor", survivorId: 42, otherId: 2, wantErr: ding.ErrNoRecord},
+		{name: "merge unknown other", survivorId: 1, otherId: 42, wantErr: ding.ErrNoRecord},
+		{
+			name:       "participant fails",
+			survivorId: 1,
+			otherId:    2,
+			participants: []ding.MergeFunc{
+				func(ctx context.Context, survivorId int64, otherId int64) error {
+					return ding.ErrDataAccess
+				},
+			},
+			wantErr: ding.ErrDataAccess,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				ctx := context.Background()
+				r := ding.Repository{Clock: FixedClock{Timestamp: timestamp}, Tm: tm}
+
+				err := r.Merge(ctx, tt.survivorId, tt.otherId, tt.participants...)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Repository.Merge() error = %v, want %v", err, tt.wantErr)
+				}
+
+				if tm.Count() != 0 {
+					t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+				}
+
+				if err != nil {
+					// Nach einem Fehler bleiben beide Dinge unverändert.
+					for _, want := range dinge {
+						got, err := r.GetById(ctx, want.Id)
+						if err != nil || got != want {
+							t.Errorf("Repository.GetById(%v) = %v, %v; want %v", want.Id, got, err, want)
+						}
+					}
+					return
+				}
+
+				survivor, err := r.GetById(ctx, tt.survivorId)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if survivor.Anzahl != dinge[0].Anzahl+dinge[1].Anzahl {
+					t.Errorf("Anzahl = %v; want %v", survivor.Anzahl, dinge[0].Anzahl+dinge[1].Anzahl)
+				}
+
+				if !survivor.Aktualisiert.Equal(timestamp) {
+					t.Errorf("Aktualisiert = %v; want %v", survivor.Aktualisiert, timestamp)
+				}
+
+				if _, err := r.GetById(ctx, tt.otherId); err == nil {
+					t.Errorf("Repository.GetById(%v) should fail after merge", tt.otherId)
+				}
+
+				byAlias, err := r.GetByCode(ctx, dinge[1].Code)
+				if err != nil || byAlias.Id != tt.survivorId {
+					t.Errorf("Repository.GetByCode(%v) = %v, %v; want %v", dinge[1].Code, byAlias.Id, err, tt.survivorId)
+				}
+
+				aliases, err := r.Aliases(ctx, tt.survivorId)
+				if err != nil || !slices.Equal(aliases, []string{dinge[1].Code}) {
+					t.Errorf("Repository.Aliases() = %v, %v; want %v", aliases, err, []string{dinge[1].Code})
+				}
+
+				history, err := r.ProductHistory(ctx, tt.survivorId, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				operations := []int{}
+				for _, event := range history {
+					operations = append(operations, event.Operation)
+				}
+
+				if !slices.Equal(operations, []int{4, 1, 1}) {
+					t.Errorf("Repository.ProductHistory() operations = %v; want %v", operations, []int{4, 1, 1})
+				}
+
+				found, err := r.Page(ctx, 12, dinge[1].Code, ding.Filter{}, "", ding.Cursor{})
+				if err != nil || len(found.Result) != 1 || found.Result[0].Id != tt.survivorId {
+					t.Errorf("Repository.Page(%v) = %v, %v; want ding %v", dinge[1].Code, found.Result, err, tt.survivorId)
+				}
+
+				// Einlagern mit dem Alias erhöht die Anzahl des überlebenden Dings.
+				result, err := r.Insert(ctx, dinge[1].Code, 1)
+				if err != nil || result.Id != tt.survivorId || result.Created {
+					t.Errorf("Repository.Insert(%v) = %v, %v; want %v", dinge[1].Code, result, err, ding.InsertResult{Id: tt.survivorId})
+				}
+
+				if _, err := r.MengeAktualisieren(ctx, dinge[1].Code, -1); err != nil {
+					t.Errorf("Repository.MengeAktualisieren(%v) error = %v", dinge[1].Code, err)
+				}
+			})
+		})
+	}
+}
+
+func TestRepository_Merge_Trash(t *testing.T) {
+	tests := []struct {
+		name       string
+		trashed    int64
+		survivorId int64
+		otherId    int64
+	}{
+		{name: "merge into trashed ding", trashed: 1, survivorId: 1, otherId: 2},
+		{name: "merge trashed ding", trashed: 2, survivorId: 1, otherId: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				ctx := context.Background()
+				r := ding.Repository{Clock: FixedClock{Timestamp: must(time.Parse(time.DateTime, "2024-12-01 10:00:00"))}, Tm: tm}
+
+				if err := r.Archivieren(ctx, tt.trashed); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := r.Merge(ctx, tt.survivorId, tt.otherId); !errors.Is(err, ding.ErrInvalidParameter) {
+					t.Errorf("Repository.Merge() error = %v, want %v", err, ding.ErrInvalidParameter)
+				}
+
+				trashed, err := r.GetById(ctx, tt.trashed)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := r.GetByCode(ctx, trashed.Code); err == nil {
+					t.Errorf("Repository.GetByCode(%v) should not find a trashed ding", trashed.Code)
+				}
+
+				if aliases, err := r.Aliases(ctx, tt.survivorId); err != nil || len(aliases) != 0 {
+					t.Errorf("Repository.Aliases() = %v, %v; want none", aliases, err)
+				}
+
+				if tm.Count() != 0 {
+					t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+				}
+			})
+		})
+	}
+}
@@ -1,62 +1,223 @@
 package ding
 
 import (
+	"context"
 	"database/sql"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/haschi/dinge/validation"
 	"github.com/haschi/dinge/webx"
 )
 
 type Module struct {
-	Repository *Repository
-	Templates  fs.FS
-	Photos     webx.Module
+	Repository  *Repository
+	Templates   fs.FS
+	Photos      webx.Module
+	PhotoUrls   PhotoLocator
+	Lookalikes  LookalikeFinder
+	PhotoMerger PhotoMerger
+
+	// TrashRetention ist die Dauer, nach der Dinge im Papierkorb endgültig gelöscht werden. Bei 0 werden sie nicht automatisch gelöscht.
+	TrashRetention time.Duration
+}
+
+// PhotoMerger überträgt beim Zusammenführen zweier Dinge das Photo des entfernten Dings.
+type PhotoMerger interface {
+	MergePhotos(ctx context.Context, survivorId int64, otherId int64) error
+}
+
+// LookalikeFinder findet Dinge, deren Photos sich ähnlich sehen.
+//
+// Dinge mit ähnlichen Photos sind möglicherweise Duplikate, die unter verschiedenen Produktcodes erfasst wurden.
+type LookalikeFinder interface {
+	// Similar liefert die Ids der Dinge, deren Photos dem Photo des Dings dingId ähnlich sehen.
+	Similar(ctx context.Context, dingId int64) ([]int64, error)
+
+	// Duplicates liefert Paare von Ids der Dinge, deren Photos sich ähnlich sehen.
+	Duplicates(ctx context.Context) ([][2]int64, error)
+}
+
+// maxLookalikes ist die größte Anzahl ähnlicher Dinge, die auf der Detailansicht und beim Bearbeiten angezeigt werden.
+const maxLookalikes = 5
+
+// lookalikes liefert die Dinge, deren Photos dem Photo des Dings dingId ähnlich sehen.
+func (m Module) lookalikes(ctx context.Context, dingId int64) ([]DingRef, error) {
+	if m.Lookalikes == nil {
+		return []DingRef{}, nil
+	}
+
+	ids, err := m.Lookalikes.Similar(ctx, dingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) > maxLookalikes {
+		ids = ids[:maxLookalikes]
+	}
+
+	return m.refs(ctx, ids)
+}
+
+// refs liefert die Dinge mit den angegebenen Ids in der Reihenfolge der Ids.
+//
+// Ids, zu denen kein Ding existiert oder deren Ding im Papierkorb liegt, werden übergangen.
+func (m Module) refs(ctx context.Context, ids []int64) ([]DingRef, error) {
+	found, err := m.Repository.GetRefs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := []DingRef{}
+	for _, id := range ids {
+		ref, ok := found[id]
+		if !ok {
+			continue
+		}
+
+		if err := m.photoUrl(ctx, &ref); err != nil {
+			return nil, err
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+// PhotoLocator liefert die URL des Photos eines Dings.
+//
+// Hat ein Ding kein Photo, liefert der PhotoLocator die URL eines Platzhalters.
+type PhotoLocator interface {
+	GetUrl(ctx context.Context, dingId int64) (string, error)
+}
+
+// photoUrl ersetzt die URL des Photos eines Dings durch die URL des [PhotoLocator], sofern einer konfiguriert ist.
+func (m Module) photoUrl(ctx context.Context, ding *DingRef) error {
+	if m.PhotoUrls == nil {
+		return nil
+	}
+
+	url, err := m.PhotoUrls.GetUrl(ctx, ding.Id)
+	if err != nil {
+		return err
+	}
+
+	ding.PhotoUrl = url
+	return nil
 }
 
 func (m *Module) Mount(mux *http.ServeMux, prefix string, middleware ...webx.Middleware) {
-	mux.Handle(fmt.Sprintf("GET %v/", prefix), webx.CombineFunc(m.Index, middleware...))
-	mux.Handle(fmt.Sprintf("GET %v/new", prefix), webx.CombineFunc(m.NewForm, middleware...))
-	mux.Handle(fmt.Sprintf("POST %v/", prefix), webx.CombineFunc(m.Create, middleware...))
-	mux.Handle(fmt.Sprintf("GET %v/{id}", prefix), webx.CombineFunc(m.Show, middleware...))
-	mux.Handle(fmt.Sprintf("GET %v/{id}/edit", prefix), webx.CombineFunc(m.Edit, middleware...))
-	mux.Handle(fmt.Sprintf("POST %v/{id}", prefix), webx.CombineFunc(m.Update, middleware...))
-	mux.Handle(fmt.Sprintf("GET %v/delete", prefix), webx.CombineFunc(m.DestroyForm, middleware...))
-	mux.Handle(fmt.Sprintf("POST %v/delete", prefix), webx.CombineFunc(m.Destroy, middleware...))
+	viewer := webx.Authorize(webx.RoleViewer, middleware...)
+	clerk := webx.Authorize(webx.RoleClerk, middleware...)
+	editor := webx.Authorize(webx.RoleEditor, middleware...)
+	admin := webx.Authorize(webx.RoleAdmin, middleware...)
+
+	mux.Handle(fmt.Sprintf("GET %v/", prefix), webx.CombineFunc(m.Index, viewer...))
+	mux.Handle(fmt.Sprintf("GET %v/new", prefix), webx.CombineFunc(m.NewForm, clerk...))
+	mux.Handle(fmt.Sprintf("GET %v/duplicates", prefix), webx.CombineFunc(m.Duplicates, viewer...))
+	mux.Handle(fmt.Sprintf("GET %v/trash", prefix), webx.CombineFunc(m.Trash, viewer...))
+	mux.Handle(fmt.Sprintf("GET %v/history", prefix), webx.CombineFunc(m.History, viewer...))
+	mux.Handle(fmt.Sprintf("POST %v/", prefix), webx.CombineFunc(m.Create, clerk...))
+	mux.Handle(fmt.Sprintf("GET %v/completions", prefix), webx.CombineFunc(m.Completions, viewer...))
+	mux.Handle(fmt.Sprintf("POST %v/searches", prefix), webx.CombineFunc(m.SaveSearch, editor...))
+	mux.Handle(fmt.Sprintf("POST %v/searches/delete", prefix), webx.CombineFunc(m.DeleteSavedSearch, editor...))
+	mux.Handle(fmt.Sprintf("GET %v/searches/export", prefix), webx.CombineFunc(m.ExportSavedSearch, admin...))
+	mux.Handle(fmt.Sprintf("GET %v/{id}", prefix), webx.CombineFunc(m.Show, viewer...))
+	mux.Handle(fmt.Sprintf("GET %v/{id}/edit", prefix), webx.CombineFunc(m.Edit, editor...))
+	mux.Handle(fmt.Sprintf("POST %v/{id}", prefix), webx.CombineFunc(m.Update, editor...))
+	mux.Handle(fmt.Sprintf("GET %v/{id}/merge", prefix), webx.CombineFunc(m.MergeForm, editor...))
+	mux.Handle(fmt.Sprintf("POST %v/{id}/merge", prefix), webx.CombineFunc(m.Merge, editor...))
+	mux.Handle(fmt.Sprintf("GET %v/{id}/archive", prefix), webx.CombineFunc(m.ArchiveForm, editor...))
+	mux.Handle(fmt.Sprintf("POST %v/{id}/archive", prefix), webx.CombineFunc(m.Archive, editor...))
+	mux.Handle(fmt.Sprintf("POST %v/{id}/restore", prefix), webx.CombineFunc(m.Restore, editor...))
+	mux.Handle(fmt.Sprintf("GET %v/{id}/purge", prefix), webx.CombineFunc(m.PurgeForm, admin...))
+	mux.Handle(fmt.Sprintf("POST %v/{id}/purge", prefix), webx.CombineFunc(m.Purge, admin...))
+	mux.Handle(fmt.Sprintf("GET %v/delete", prefix), webx.CombineFunc(m.DestroyForm, clerk...))
+	mux.Handle(fmt.Sprintf("POST %v/delete", prefix), webx.CombineFunc(m.Destroy, clerk...))
 }
 
+// pageSize ist die Anzahl der Dinge auf einer Seite der Übersicht.
+const pageSize = 12
+
 // Zeigt eine Liste aller Dinge
 func (m Module) Index(w http.ResponseWriter, r *http.Request) {
-
-	content := webx.TemplateData[IndexFormData]{}
-
 	form := validation.NewForm(r)
 	defer form.Close()
 
-	sortOptions := validation.StringOptions("alpha", "omega", "latest", "oldest")
+	var search SavedSearch
+	var c string
+	scanSearch(form, &search, validation.String("c", &c))
 
-	form.Scan(
-		validation.String("q", &content.FormValues.Q, validation.MaxLength(100)),
-		validation.String("s", &content.FormValues.S, sortOptions),
-	)
+	// Ein ungültiger Cursor oder ein Cursor einer anderen Sortierung beginnt mit der ersten Seite.
+	cursor, err := ParseCursor(c)
+	if err != nil || cursor.Sort != search.S {
+		cursor = Cursor{}
+	}
+
+	m.renderIndex(w, r, search, cursor, form.ValidationErrors)
+}
+
+// renderIndex zeigt die Seite des Suchergebnisses von search an der Position cursor.
+//
+// Enthält validationErrors Fehler, werden diese angezeigt und die Antwort hat den Status 422.
+func (m Module) renderIndex(w http.ResponseWriter, r *http.Request, search SavedSearch, cursor Cursor, validationErrors validation.ErrorMap) {
+	page, err := m.Repository.Page(r.Context(), pageSize, search.Q, search.Filter, search.S, cursor)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
 
-	dinge, err := m.Repository.Search(r.Context(), 12, content.FormValues.Q, content.FormValues.S)
+	for i := range page.Result {
+		if err := m.photoUrl(r.Context(), &page.Result[i]); err != nil {
+			webx.ServerError(w, err)
+			return
+		}
+	}
+
+	searches, err := m.Repository.SavedSearches(r.Context())
 	if err != nil {
 		webx.ServerError(w, err)
 		return
 	}
 
-	content.FormValues.Result = dinge
+	principal, _ := webx.PrincipalFrom(r.Context())
+
+	content := webx.TemplateData[IndexFormData]{
+		Scripts: []string{"/static/completion.js"},
+		FormValues: IndexFormData{
+			Principal:     principal,
+			Q:             search.Q,
+			S:             search.S,
+			Filter:        search.Filter,
+			Name:          search.Name,
+			Result:        page.Result,
+			Highlights:    page.Highlights,
+			Suggestion:    page.Suggestion,
+			Next:          page.Next.String(),
+			Previous:      page.Previous.String(),
+			SavedSearches: searches,
+		},
+	}
+
+	status := http.StatusOK
+	if len(validationErrors) > 0 {
+		content.ValidationErrors = validationErrors
+		status = http.StatusUnprocessableEntity
+	}
 
-	// TODO: Validierungsfehler in index.tmpl anzeigen
 	response := webx.HtmlResponse[IndexFormData]{
 		TemplateName: "index",
 		Data:         content,
-		StatusCode:   http.StatusOK,
+		StatusCode:   status,
 	}
 
 	if err := response.Render(w, m.Templates); err != nil {
@@ -64,6 +225,196 @@ func (m Module) Index(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// scanSearch liest die Parameter einer Suche der Übersicht und die zusätzlichen Parameter scanners aus der Anfrage.
+//
+// Eine unbekannte Sortierung wird ohne Fehler durch die voreingestellte ersetzt. Ungültige Filter werden als Fehler gemeldet und nicht angewendet.
+func scanSearch(form *validation.Form, search *SavedSearch, scanners ...validation.ScanFunc) {
+	sortOptions := validation.StringOptions("alpha", "omega", "latest", "oldest", "relevance")
+
+	filter := &search.Filter
+	form.Scan(append([]validation.ScanFunc{
+		validation.String("q", &search.Q, validation.MaxLength(100)),
+		validation.String("s", &search.S, sortOptions),
+		validation.Boolean("leer", &filter.Leer),
+		validation.Optional("unter", validation.Integer("unter", &filter.Unter, validation.Min(1))),
+		validation.Optional("seit", validation.Date("seit", &filter.Seit)),
+		validation.Optional("bis", validation.Date("bis", &filter.Bis)),
+		validation.Optional("photo", validation.String("photo", &filter.Photo, validation.StringOptions(PhotoOptions...))),
+		validation.Boolean("archiviert", &filter.Archiviert),
+	}, scanners...)...)
+
+	if _, invalid := form.ValidationErrors["s"]; invalid {
+		search.S = ""
+		delete(form.ValidationErrors, "s")
+	}
+
+	for _, key := range []string{"unter", "seit", "bis", "photo"} {
+		if _, invalid := form.ValidationErrors[key]; invalid {
+			resetFilter(filter, key)
+		}
+	}
+}
+
+// resetFilter setzt den Filter key zurück, dessen Wert ungültig ist.
+func resetFilter(filter *Filter, key string) {
+	switch key {
+	case "unter":
+		filter.Unter = 0
+	case "seit":
+		filter.Seit = time.Time{}
+	case "bis":
+		filter.Bis = time.Time{}
+	case "photo":
+		filter.Photo = ""
+	}
+}
+
+// completionLimit ist die Anzahl der Vorschläge, die Completions höchstens liefert.
+const completionLimit = 8
+
+// completionMaxAge ist die Dauer in Sekunden, die ein Browser die Vorschläge zu einer Eingabe wiederverwenden darf.
+const completionMaxAge = 60
+
+// Completions liefert Vorschläge für die Eingabe q als JSON Array mit Name und Code.
+//
+// Die Vorschläge dürfen vom Browser kurz zwischengespeichert werden, so dass wiederholte Eingaben während des Tippens keine Anfragen auslösen.
+func (m Module) Completions(w http.ResponseWriter, r *http.Request) {
+	form := validation.NewForm(r)
+	defer form.Close()
+
+	var q string
+	form.Scan(validation.String("q", &q, validation.MaxLength(100)))
+
+	if !form.IsValid() {
+		response := webx.JsonResponse[validation.ErrorMap]{
+			Data:       form.ValidationErrors,
+			StatusCode: http.StatusUnprocessableEntity,
+		}
+
+		if err := response.Render(w); err != nil {
+			webx.ServerError(w, err)
+		}
+		return
+	}
+
+	completions, err := m.Repository.Complete(r.Context(), q, completionLimit)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", completionMaxAge))
+	response := webx.JsonResponse[[]Completion]{
+		Data:       completions,
+		StatusCode: http.StatusOK,
+	}
+
+	if err := response.Render(w); err != nil {
+		webx.ServerError(w, err)
+	}
+}
+
+// SaveSearch speichert die Suche der Übersicht unter einem Namen und leitet zu ihrem Ergebnis weiter.
+func (m Module) SaveSearch(w http.ResponseWriter, r *http.Request) {
+	form := validation.NewForm(r)
+	defer form.Close()
+
+	var search SavedSearch
+	scanSearch(form, &search, validation.String("name", &search.Name, validation.IsNotBlank, validation.MaxLength(100)))
+
+	if !form.IsValid() {
+		m.renderIndex(w, r, search, Cursor{}, form.ValidationErrors)
+		return
+	}
+
+	if _, err := m.Repository.SaveSearch(r.Context(), search); err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	webx.SeeOther("%v", search.Url()).ServeHTTP(w, r)
+}
+
+// DeleteSavedSearch löscht die gespeicherte Suche id und leitet zur Übersicht weiter.
+//
+// Die Id steht im Formular und nicht im Pfad, weil ein Pfad /dinge/searches/{id}/delete mit den Routen des Photo Moduls unter /dinge/{id}/photo kollidiert.
+func (m Module) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	form := validation.NewForm(r)
+	defer form.Close()
+
+	var id int64
+	if err := form.Scan(validation.Integer64("id", &id)); err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	if !form.IsValid() || id < 1 {
+		webx.NotFound(w, r, m.Templates)
+		return
+	}
+
+	if err := m.Repository.DeleteSavedSearch(r.Context(), id); err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			webx.NotFound(w, r, m.Templates)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	webx.SeeOther("/dinge/").ServeHTTP(w, r)
+}
+
+// ExportSavedSearch liefert das Ergebnis der gespeicherten Suche id als CSV Datei.
+//
+// Wie bei [Module.DeleteSavedSearch] steht die Id im Parameter id und nicht im Pfad. Die Dinge werden während des Exports aus der Datenbank gelesen. Tritt dabei ein Fehler auf, bricht der Export die Verbindung ab, damit kein unvollständiges Ergebnis als vollständig erscheint.
+func (m Module) ExportSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id < 1 {
+		webx.NotFound(w, r, m.Templates)
+		return
+	}
+
+	search, err := m.Repository.GetSavedSearch(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			webx.NotFound(w, r, m.Templates)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": search.Name + ".csv"}))
+
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"id", "code", "name", "anzahl"}); err != nil {
+		webx.LoggerFrom(r.Context()).Error("exporting saved search", slog.String("error", err.Error()))
+		return
+	}
+
+	for ding, err := range m.Repository.SearchSaved(r.Context(), id) {
+		if err != nil {
+			webx.LoggerFrom(r.Context()).Error("exporting saved search", slog.String("error", err.Error()))
+			panic(http.ErrAbortHandler)
+		}
+
+		record := []string{strconv.FormatInt(ding.Id, 10), ding.Code, ding.Name, strconv.Itoa(ding.Anzahl)}
+		if err := out.Write(record); err != nil {
+			webx.LoggerFrom(r.Context()).Error("exporting saved search", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		webx.LoggerFrom(r.Context()).Error("exporting saved search", slog.String("error", err.Error()))
+	}
+}
+
 // Liefert eine HTML Form zum Einlagern eines neuen Dings.
 func (m Module) NewForm(w http.ResponseWriter, r *http.Request) {
 
@@ -152,14 +503,14 @@ func (m Module) Show(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil || id < 1 {
-		http.NotFound(w, r)
+		webx.NotFound(w, r, m.Templates)
 		return
 	}
 
 	ding, err := m.Repository.GetById(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.NotFound(w, r)
+			webx.NotFound(w, r, m.Templates)
 			return
 		}
 
@@ -167,16 +518,35 @@ func (m Module) Show(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := m.photoUrl(r.Context(), &ding.DingRef); err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
 	history, err := m.Repository.ProductHistory(r.Context(), id, 10)
 	if err != nil {
 		webx.ServerError(w, err)
 		return
 	}
 
+	lookalikes, err := m.lookalikes(r.Context(), id)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	aliases, err := m.Repository.Aliases(r.Context(), id)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
 	data := webx.TemplateData[ShowResponseData]{
 		FormValues: ShowResponseData{
-			Ding:    ding,
-			History: history,
+			Ding:       ding,
+			History:    history,
+			Lookalikes: lookalikes,
+			Aliases:    aliases,
 		},
 	}
 
@@ -191,18 +561,183 @@ func (m Module) Show(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Duplicates zeigt Paare von Dingen, deren Photos sich ähnlich sehen.
+func (m Module) Duplicates(w http.ResponseWriter, r *http.Request) {
+	data := webx.TemplateData[DuplicatesResponseData]{
+		FormValues: DuplicatesResponseData{Pairs: []DuplicatePair{}},
+	}
+
+	if m.Lookalikes != nil {
+		pairs, err := m.Lookalikes.Duplicates(r.Context())
+		if err != nil {
+			webx.ServerError(w, err)
+			return
+		}
+
+		ids := []int64{}
+		for _, pair := range pairs {
+			ids = append(ids, pair[:]...)
+		}
+
+		slices.Sort(ids)
+		refs, err := m.refs(r.Context(), slices.Compact(ids))
+		if err != nil {
+			webx.ServerError(w, err)
+			return
+		}
+
+		found := map[int64]DingRef{}
+		for _, ref := range refs {
+			found[ref.Id] = ref
+		}
+
+		for _, pair := range pairs {
+			first, ok := found[pair[0]]
+			if !ok {
+				continue
+			}
+
+			second, ok := found[pair[1]]
+			if !ok {
+				continue
+			}
+
+			data.FormValues.Pairs = append(data.FormValues.Pairs, DuplicatePair{First: first, Second: second})
+		}
+	}
+
+	response := webx.HtmlResponse[DuplicatesResponseData]{
+		TemplateName: "duplicates",
+		Data:         data,
+		StatusCode:   http.StatusOK,
+	}
+
+	if err := response.Render(w, m.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
+
+// MergeForm zeigt eine Form, um ein anderes Ding mit dem Ding {id} zusammenzuführen.
+//
+// Der Parameter code der Anfrage belegt den Produktcode des anderen Dings vor.
+func (m Module) MergeForm(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		webx.NotFound(w, r, m.Templates)
+		return
+	}
+
+	ding, err := m.Repository.GetById(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			webx.NotFound(w, r, m.Templates)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	data := webx.TemplateData[MergeResponseData]{
+		FormValues: MergeResponseData{Ding: ding, OtherCode: r.URL.Query().Get(Code)},
+	}
+
+	m.renderMerge(w, data, http.StatusOK)
+}
+
+// Merge führt das Ding mit dem übermittelten Produktcode mit dem Ding {id} zusammen.
+//
+// Ziel der Form von [Module.MergeForm]. Nach dem Zusammenführen wird zur Detailansicht des Dings {id} weitergeleitet.
+func (m Module) Merge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		webx.NotFound(w, r, m.Templates)
+		return
+	}
+
+	ding, err := m.Repository.GetById(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			webx.NotFound(w, r, m.Templates)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	form := validation.NewForm(r)
+	defer form.Close()
+
+	data := webx.TemplateData[MergeResponseData]{
+		FormValues:       MergeResponseData{Ding: ding},
+		ValidationErrors: form.ValidationErrors,
+	}
+
+	if err := form.Scan(validation.String(Code, &data.FormValues.OtherCode, validation.IsNotBlank)); err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	if form.IsValid() && ding.Archiviert.Valid {
+		form.ValidationErrors[Code] = "Ein Ding im Papierkorb kann nicht zusammengeführt werden"
+	}
+
+	if form.IsValid() {
+		other, err := m.Repository.GetByCode(r.Context(), data.FormValues.OtherCode)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			form.ValidationErrors[Code] = "Unbekannter Produktcode"
+		case err != nil:
+			webx.ServerError(w, err)
+			return
+		case other.Id == id:
+			form.ValidationErrors[Code] = "Ein Ding kann nicht mit sich selbst zusammengeführt werden"
+		}
+
+		if form.IsValid() {
+			var participants []MergeFunc
+			if m.PhotoMerger != nil {
+				participants = append(participants, m.PhotoMerger.MergePhotos)
+			}
+
+			if err := m.Repository.Merge(r.Context(), id, other.Id, participants...); err != nil {
+				webx.ServerError(w, err)
+				return
+			}
+
+			webx.SeeOther("/dinge/%v", id).ServeHTTP(w, r)
+			return
+		}
+	}
+
+	m.renderMerge(w, data, http.StatusUnprocessableEntity)
+}
+
+func (m Module) renderMerge(w http.ResponseWriter, data webx.TemplateData[MergeResponseData], status int) {
+	response := webx.HtmlResponse[MergeResponseData]{
+		TemplateName: "merge",
+		Data:         data,
+		StatusCode:   status,
+	}
+
+	if err := response.Render(w, m.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
+
 // Edit zeigt eine Form zum Bearbeiten eines spezifischen Dings
 func (m Module) Edit(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil || id < 1 {
-		http.NotFound(w, r)
+		webx.NotFound(w, r, m.Templates)
 		return
 	}
 
 	ding, err := m.Repository.GetById(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.NotFound(w, r)
+			webx.NotFound(w, r, m.Templates)
 			return
 		}
 
@@ -210,12 +745,23 @@ func (m Module) Edit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := webx.TemplateData[Ding]{
+	if err := m.photoUrl(r.Context(), &ding.DingRef); err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	lookalikes, err := m.lookalikes(r.Context(), id)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	data := webx.TemplateData[EditResponseData]{
 		Scripts:    []string{"/static/photo.js"},
-		FormValues: ding,
+		FormValues: EditResponseData{Ding: ding, Lookalikes: lookalikes},
 	}
 
-	response := webx.HtmlResponse[Ding]{
+	response := webx.HtmlResponse[EditResponseData]{
 		TemplateName: "edit",
 		Data:         data,
 		StatusCode:   http.StatusOK,
@@ -231,7 +777,7 @@ func (m Module) Update(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil || id < 1 {
-		http.NotFound(w, r)
+		webx.NotFound(w, r, m.Templates)
 		return
 	}
 
@@ -258,16 +804,27 @@ func (m Module) Update(w http.ResponseWriter, r *http.Request) {
 		ding, err := m.Repository.GetById(r.Context(), id)
 		if err != nil {
 			// TODO: Fehler differenzieren.
-			http.NotFound(w, r)
+			webx.NotFound(w, r, m.Templates)
+			return
+		}
+
+		if err := m.photoUrl(r.Context(), &ding.DingRef); err != nil {
+			webx.ServerError(w, err)
 			return
 		}
 
-		data := webx.TemplateData[Ding]{
-			FormValues:       ding,
+		lookalikes, err := m.lookalikes(r.Context(), id)
+		if err != nil {
+			webx.ServerError(w, err)
+			return
+		}
+
+		data := webx.TemplateData[EditResponseData]{
+			FormValues:       EditResponseData{Ding: ding, Lookalikes: lookalikes},
 			ValidationErrors: form.ValidationErrors,
 		}
 
-		response := webx.HtmlResponse[Ding]{
+		response := webx.HtmlResponse[EditResponseData]{
 			TemplateName: "edit",
 			Data:         data,
 			StatusCode:   http.StatusUnprocessableEntity,
@@ -292,7 +849,7 @@ func (m Module) Update(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		if errors.Is(err, ErrNoRecord) {
-			http.NotFound(w, r)
+			webx.NotFound(w, r, m.Templates)
 			return
 		}
 		webx.ServerError(w, err)
@@ -383,3 +940,180 @@ func (m Module) DestroyForm(w http.ResponseWriter, r *http.Request) {
 		webx.ServerError(w, err)
 	}
 }
+
+// Trash zeigt die Dinge im Papierkorb.
+func (m Module) Trash(w http.ResponseWriter, r *http.Request) {
+	dinge, err := m.Repository.Papierkorb(r.Context(), 100)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	principal, _ := webx.PrincipalFrom(r.Context())
+
+	data := webx.TemplateData[TrashResponseData]{
+		FormValues: TrashResponseData{Items: []TrashItem{}, Principal: principal},
+	}
+
+	for _, ding := range dinge {
+		if err := m.photoUrl(r.Context(), &ding.DingRef); err != nil {
+			webx.ServerError(w, err)
+			return
+		}
+
+		item := TrashItem{Ding: ding}
+		if m.TrashRetention > 0 {
+			item.Loeschdatum = ding.Archiviert.Time.Add(m.TrashRetention)
+		}
+
+		data.FormValues.Items = append(data.FormValues.Items, item)
+	}
+
+	response := webx.HtmlResponse[TrashResponseData]{
+		TemplateName: "trash",
+		Data:         data,
+		StatusCode:   http.StatusOK,
+	}
+
+	if err := response.Render(w, m.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
+
+// historyLimit ist die größte Anzahl von Ereignissen, die die Historie zeigt.
+const historyLimit = 100
+
+// History zeigt die letzten Änderungen an allen Dingen. Der Parameter actor beschränkt die Historie auf die Änderungen eines Akteurs.
+func (m Module) History(w http.ResponseWriter, r *http.Request) {
+	form := validation.NewForm(r)
+	defer form.Close()
+
+	var data HistoryResponseData
+	form.Scan(validation.String("actor", &data.Actor, validation.MaxLength(100)))
+
+	actors, err := m.Repository.Actors(r.Context())
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	data.Actors = actors
+
+	if data.Actor == "" || !form.IsValid() {
+		data.Events, err = m.Repository.GetAllEvents(r.Context(), historyLimit)
+	} else {
+		data.Events, err = m.Repository.ActorHistory(r.Context(), data.Actor, historyLimit)
+	}
+
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !form.IsValid() {
+		status = http.StatusUnprocessableEntity
+	}
+
+	response := webx.HtmlResponse[HistoryResponseData]{
+		TemplateName: "history",
+		Data: webx.TemplateData[HistoryResponseData]{
+			FormValues:       data,
+			ValidationErrors: form.ValidationErrors,
+		},
+		StatusCode: status,
+	}
+
+	if err := response.Render(w, m.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
+
+// ArchiveForm fragt nach, ob das Ding {id} in den Papierkorb gelegt werden soll.
+func (m Module) ArchiveForm(w http.ResponseWriter, r *http.Request) {
+	m.confirm(w, r, "archive")
+}
+
+// Archive legt das Ding {id} in den Papierkorb und leitet zur Übersicht weiter.
+func (m Module) Archive(w http.ResponseWriter, r *http.Request) {
+	if _, ok := m.changeTrash(w, r, m.Repository.Archivieren); ok {
+		webx.SeeOther("/dinge/").ServeHTTP(w, r)
+	}
+}
+
+// Restore holt das Ding {id} aus dem Papierkorb zurück und leitet zur Detailansicht weiter.
+func (m Module) Restore(w http.ResponseWriter, r *http.Request) {
+	if id, ok := m.changeTrash(w, r, m.Repository.Wiederherstellen); ok {
+		webx.SeeOther("/dinge/%v", id).ServeHTTP(w, r)
+	}
+}
+
+// PurgeForm fragt nach, ob das Ding {id} endgültig gelöscht werden soll.
+func (m Module) PurgeForm(w http.ResponseWriter, r *http.Request) {
+	m.confirm(w, r, "purge")
+}
+
+// Purge löscht das Ding {id} aus dem Papierkorb endgültig und leitet zum Papierkorb weiter.
+func (m Module) Purge(w http.ResponseWriter, r *http.Request) {
+	if _, ok := m.changeTrash(w, r, m.Repository.Entfernen); ok {
+		webx.SeeOther("/dinge/trash").ServeHTTP(w, r)
+	}
+}
+
+// confirm zeigt die Seite templateName, auf der eine Aktion für das Ding {id} bestätigt wird.
+func (m Module) confirm(w http.ResponseWriter, r *http.Request, templateName string) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		webx.NotFound(w, r, m.Templates)
+		return
+	}
+
+	ding, err := m.Repository.GetById(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			webx.NotFound(w, r, m.Templates)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	if err := m.photoUrl(r.Context(), &ding.DingRef); err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	response := webx.HtmlResponse[Ding]{
+		TemplateName: templateName,
+		Data:         webx.TemplateData[Ding]{FormValues: ding},
+		StatusCode:   http.StatusOK,
+	}
+
+	if err := response.Render(w, m.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
+
+// changeTrash führt die Aktion change für das Ding {id} aus.
+//
+// Schlägt die Aktion fehl, schreibt changeTrash die Fehlerantwort und liefert false.
+func (m Module) changeTrash(w http.ResponseWriter, r *http.Request, change func(context.Context, int64) error) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		webx.NotFound(w, r, m.Templates)
+		return id, false
+	}
+
+	if err := change(r.Context(), id); err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			webx.NotFound(w, r, m.Templates)
+			return id, false
+		}
+
+		webx.ServerError(w, err)
+		return id, false
+	}
+
+	return id, true
+}
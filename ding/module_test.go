@@ -1,16 +1,23 @@
 package ding_test
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/haschi/dinge/ding"
 	"github.com/haschi/dinge/sqlx"
 	"github.com/haschi/dinge/system"
 	"github.com/haschi/dinge/templates"
+	"github.com/haschi/dinge/validation"
 	"github.com/haschi/dinge/webx"
 	"golang.org/x/net/html"
 )
@@ -44,6 +51,11 @@ func TestModule_GetDinge(t *testing.T) {
 			args: args{url: "/dinge/?q=paprika&s=alpha"},
 			want: want{q: "paprika", s: "alpha", status: http.StatusOK},
 		},
+		{
+			name: "Mit ungültigem Cursor",
+			args: args{url: "/dinge/?s=alpha&c=malformed"},
+			want: want{q: "", s: "alpha", status: http.StatusOK},
+		},
 	}
 
 	config := newTestConfig()
@@ -86,6 +98,46 @@ func TestModule_GetDinge(t *testing.T) {
 	}
 }
 
+func TestModule_GetDinge_Escaped(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	tests := []struct {
+		name           string
+		q              string
+		wantSuggestion bool
+	}{
+		{name: "no result", q: "<script>alert(1)</script>"},
+		{name: "suggestion", q: "tomatte <script>alert(1)</script>", wantSuggestion: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := "/dinge/?" + url.Values{"q": []string{test.q}}.Encode()
+			response := testserver.Get(path)
+			defer response.Body.Close()
+
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(string(body), "<script>alert(1)</script>") {
+				t.Errorf("GET %v contains the unescaped query", path)
+			}
+
+			if !strings.Contains(string(body), "&lt;script&gt;alert(1)&lt;/script&gt;") {
+				t.Errorf("GET %v does not contain the escaped query", path)
+			}
+
+			if got := strings.Contains(string(body), `id="suggestion"`); got != test.wantSuggestion {
+				t.Errorf("GET %v contains suggestion = %v; want %v", path, got, test.wantSuggestion)
+			}
+		})
+	}
+}
+
 func TestModule_GetDingeNew(t *testing.T) {
 	config := newTestConfig()
 	testserver := webx.NewTestserver(t, "/dinge", config)
@@ -137,6 +189,160 @@ func TestModule_GetDingeId(t *testing.T) {
 	}
 }
 
+func TestModule_GetDingeIdPhotoUrl(t *testing.T) {
+
+	config := newTestConfig()
+	config.Module = func(db *sql.DB) (webx.Module, error) {
+		module, err := newDingTestModule(sqlx.Execute(ding.CreateScript, ding.FixtureScript))(db)
+		if err != nil {
+			return nil, err
+		}
+
+		module.(*ding.Module).PhotoUrls = PhotoLocatorFunc(func(ctx context.Context, id int64) (string, error) {
+			return "/static/placeholder.svg", nil
+		})
+
+		return module, nil
+	}
+
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	for _, path := range []string{"/dinge/1", "/dinge/1/edit"} {
+		t.Run(path, func(t *testing.T) {
+			response := testserver.Get(path)
+			defer response.Body.Close()
+
+			doc, err := html.Parse(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			images := webx.GetElement(doc, "img")
+			if len(images) != 1 {
+				t.Fatalf("GET %v contains %v <img> elements; want 1", path, len(images))
+			}
+
+			if src := webx.GetAttributeValue(images[0], "src"); src != "/static/placeholder.svg" {
+				t.Errorf("GET %v <img src='%v'>; want %v", path, src, "/static/placeholder.svg")
+			}
+		})
+	}
+}
+
+func TestModule_GetDingeIndexPhotoUrl(t *testing.T) {
+
+	config := newTestConfig()
+	config.Module = func(db *sql.DB) (webx.Module, error) {
+		module, err := newDingTestModule(sqlx.Execute(ding.CreateScript, ding.FixtureScript))(db)
+		if err != nil {
+			return nil, err
+		}
+
+		module.(*ding.Module).PhotoUrls = PhotoLocatorFunc(func(ctx context.Context, id int64) (string, error) {
+			return fmt.Sprintf("/photos/%v?v=hash%v", id, id), nil
+		})
+
+		return module, nil
+	}
+
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	response := testserver.Get("/dinge/")
+	defer response.Body.Close()
+
+	doc, err := html.Parse(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images := webx.GetElement(doc, "img")
+	if len(images) == 0 {
+		t.Fatal("GET /dinge/ contains no <img> elements")
+	}
+
+	for _, image := range images {
+		if src := webx.GetAttributeValue(image, "src"); !strings.Contains(src, "?v=hash") {
+			t.Errorf("GET /dinge/ <img src='%v'>; want versioned URL of the PhotoLocator", src)
+		}
+	}
+}
+
+// PhotoLocatorFunc ist eine Funktion, die als [ding.PhotoLocator] verwendet werden kann.
+type PhotoLocatorFunc func(ctx context.Context, id int64) (string, error)
+
+func (fn PhotoLocatorFunc) GetUrl(ctx context.Context, id int64) (string, error) {
+	return fn(ctx, id)
+}
+
+func TestModule_Lookalikes(t *testing.T) {
+
+	config := newTestConfig()
+	config.Module = func(db *sql.DB) (webx.Module, error) {
+		module, err := newDingTestModule(sqlx.Execute(ding.CreateScript, ding.FixtureScript))(db)
+		if err != nil {
+			return nil, err
+		}
+
+		module.(*ding.Module).Lookalikes = Lookalikes{1: {2}, 2: {1}}
+		return module, nil
+	}
+
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	tests := []struct {
+		path     string
+		wantLink string
+	}{
+		{path: "/dinge/1", wantLink: "/dinge/2"},
+		{path: "/dinge/1/edit", wantLink: "/dinge/2"},
+		{path: "/dinge/duplicates", wantLink: "/dinge/2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			response := testserver.Get(tt.path)
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("GET %v = %v; want %v", tt.path, response.StatusCode, http.StatusOK)
+			}
+
+			doc, err := html.Parse(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.ContainsFunc(webx.GetElement(doc, "a"), func(n *html.Node) bool {
+				return webx.GetAttributeValue(n, "href") == tt.wantLink
+			}) {
+				t.Errorf("GET %v contains no link to %v", tt.path, tt.wantLink)
+			}
+		})
+	}
+}
+
+// Lookalikes ist ein [ding.LookalikeFinder] für Testzwecke, der die Ids ähnlicher Dinge einer Map entnimmt.
+type Lookalikes map[int64][]int64
+
+func (l Lookalikes) Similar(ctx context.Context, dingId int64) ([]int64, error) {
+	return l[dingId], nil
+}
+
+func (l Lookalikes) Duplicates(ctx context.Context) ([][2]int64, error) {
+	pairs := [][2]int64{}
+	for id, similar := range l {
+		for _, other := range similar {
+			if id < other {
+				pairs = append(pairs, [2]int64{id, other})
+			}
+		}
+	}
+	return pairs, nil
+}
+
 func TestModule_GetDingeIdEdit(t *testing.T) {
 	config := newTestConfig()
 	testserver := webx.NewTestserver(t, "/dinge", config)
@@ -403,3 +609,492 @@ func newTestConfig() webx.TestserverConfig {
 
 	return config
 }
+
+func TestModule_PostDingeIdMerge_Trash(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	if resp := testserver.Post("/dinge/3/archive", url.Values{}); resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("POST /dinge/3/archive = %v, want %v", resp.StatusCode, http.StatusSeeOther)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code string
+	}{
+		{name: "merge trashed thing", path: "/dinge/1/merge", code: "333"},
+		{name: "merge into trashed thing", path: "/dinge/3/merge", code: "222"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := testserver.Post(test.path, url.Values{ding.Code: []string{test.code}})
+			if resp.StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("POST %v = %v, want %v", test.path, resp.StatusCode, http.StatusUnprocessableEntity)
+			}
+		})
+	}
+
+	for _, path := range []string{"/dinge/2", "/dinge/3"} {
+		if resp := testserver.Get(path); resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %v = %v, want %v", path, resp.StatusCode, http.StatusOK)
+		}
+	}
+}
+
+func TestModule_PostDingeIdMerge(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		code           string
+		wantStatusCode int
+		wantLocation   string
+	}{
+		{name: "unknown code", path: "/dinge/1/merge", code: "unknown", wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "blank code", path: "/dinge/1/merge", code: "", wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "merge with itself", path: "/dinge/1/merge", code: "111", wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "unknown thing", path: "/dinge/42/merge", code: "222", wantStatusCode: http.StatusNotFound},
+		{name: "merge known thing", path: "/dinge/1/merge", code: "222", wantStatusCode: http.StatusSeeOther, wantLocation: "/dinge/1"},
+		{name: "merge alias of itself", path: "/dinge/1/merge", code: "222", wantStatusCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := testserver.Post(test.path, url.Values{ding.Code: []string{test.code}})
+
+			if resp.StatusCode != test.wantStatusCode {
+				t.Errorf("POST %v = %v, want %v", test.path, resp.StatusCode, test.wantStatusCode)
+			}
+
+			location := resp.Header.Get("Location")
+			if location != test.wantLocation {
+				t.Errorf("POST %v = %v, want %v", test.path, location, test.wantLocation)
+			}
+		})
+	}
+
+	resp := testserver.Get("/dinge/2")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /dinge/2 = %v, want %v", resp.StatusCode, http.StatusNotFound)
+	}
+
+	for _, path := range []string{"/dinge/1", "/dinge/1/merge?code=333"} {
+		resp := testserver.Get(path)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %v = %v, want %v", path, resp.StatusCode, http.StatusOK)
+		}
+	}
+}
+
+func TestModule_Trash(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		wantStatusCode int
+		wantLocation   string
+	}{
+		{name: "confirm archive", method: http.MethodGet, path: "/dinge/1/archive", wantStatusCode: http.StatusOK},
+		{name: "archive", method: http.MethodPost, path: "/dinge/1/archive", wantStatusCode: http.StatusSeeOther, wantLocation: "/dinge/"},
+		{name: "archive again", method: http.MethodPost, path: "/dinge/1/archive", wantStatusCode: http.StatusNotFound},
+		{name: "show trash", method: http.MethodGet, path: "/dinge/trash", wantStatusCode: http.StatusOK},
+		{name: "show archived", method: http.MethodGet, path: "/dinge/1", wantStatusCode: http.StatusOK},
+		{name: "restore", method: http.MethodPost, path: "/dinge/1/restore", wantStatusCode: http.StatusSeeOther, wantLocation: "/dinge/1"},
+		{name: "purge ding not in trash", method: http.MethodPost, path: "/dinge/1/purge", wantStatusCode: http.StatusNotFound},
+		{name: "archive again after restore", method: http.MethodPost, path: "/dinge/1/archive", wantStatusCode: http.StatusSeeOther, wantLocation: "/dinge/"},
+		{name: "confirm purge", method: http.MethodGet, path: "/dinge/1/purge", wantStatusCode: http.StatusOK},
+		{name: "purge", method: http.MethodPost, path: "/dinge/1/purge", wantStatusCode: http.StatusSeeOther, wantLocation: "/dinge/trash"},
+		{name: "show purged", method: http.MethodGet, path: "/dinge/1", wantStatusCode: http.StatusNotFound},
+		{name: "restore unknown", method: http.MethodPost, path: "/dinge/42/restore", wantStatusCode: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			var resp *http.Response
+			if step.method == http.MethodPost {
+				resp = testserver.Post(step.path, url.Values{})
+			} else {
+				resp = testserver.Get(step.path)
+			}
+
+			if resp.StatusCode != step.wantStatusCode {
+				t.Errorf("%v %v = %v, want %v", step.method, step.path, resp.StatusCode, step.wantStatusCode)
+			}
+
+			location := resp.Header.Get("Location")
+			if location != step.wantLocation {
+				t.Errorf("%v %v = %v, want %v", step.method, step.path, location, step.wantLocation)
+			}
+		})
+	}
+}
+
+func TestModule_Trash_PurgeLink(t *testing.T) {
+	tests := []struct {
+		name string
+		role webx.Role
+		want bool
+	}{
+		{name: "editor", role: webx.RoleEditor, want: false},
+		{name: "admin", role: webx.RoleAdmin, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig()
+			config.Principal = webx.Principal{Id: 1, Name: "anna", Role: tt.role}
+			testserver := webx.NewTestserver(t, "/dinge", config)
+			defer testserver.Close()
+
+			resp := testserver.Post("/dinge/1/archive", url.Values{})
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusSeeOther {
+				t.Fatalf("POST /dinge/1/archive = %v, want %v", resp.StatusCode, http.StatusSeeOther)
+			}
+
+			resp = testserver.Get("/dinge/trash")
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.Contains(string(body), "/dinge/1/purge"); got != tt.want {
+				t.Errorf("GET /dinge/trash contains purge link = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_GetDingeHistory(t *testing.T) {
+	config := newTestConfig()
+	config.Principal = webx.Principal{Id: 1, Name: "anna", Role: webx.RoleClerk}
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	resp := testserver.Post("/dinge/delete", url.Values{ding.Code: {"111"}, ding.Anzahl: {"1"}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("POST /dinge/delete = %v, want %v", resp.StatusCode, http.StatusSeeOther)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantRows int
+	}{
+		{name: "all actors", path: "/dinge/history", wantRows: 4},
+		{name: "by actor", path: "/dinge/history?actor=anna", wantRows: 1},
+		{name: "unknown actor", path: "/dinge/history?actor=bert", wantRows: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := testserver.Get(tt.path)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET %v = %v, want %v", tt.path, resp.StatusCode, http.StatusOK)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Die erste Zeile der Tabelle ist die Überschrift.
+			rows := max(strings.Count(string(body), "<tr>")-1, 0)
+
+			if rows != tt.wantRows {
+				t.Errorf("GET %v = %v rows, want %v", tt.path, rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestModule_GetDinge_Query(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		sort     string
+		wantMark string
+	}{
+		{name: "prefix is highlighted", query: "pap", wantMark: "<mark>Paprika</mark>"},
+		{name: "quotes and dashes", query: `"-pap`, wantMark: "<mark>Paprika</mark>"},
+		{name: "field filter and relevance", query: "code:111", sort: "relevance", wantMark: "<mark>111</mark>"},
+		{name: "did you mean", query: "Papirka", wantMark: `Meintest du <a href="/dinge/?q=paprika&s=">paprika</a>?`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/dinge/?" + url.Values{"q": {tt.query}, "s": {tt.sort}}.Encode()
+			response := testserver.Get(path)
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("GET %v = %v; want %v", path, response.StatusCode, http.StatusOK)
+			}
+
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(string(body), tt.wantMark) {
+				t.Errorf("GET %v does not contain %v", path, tt.wantMark)
+			}
+		})
+	}
+}
+
+func TestModule_GetDinge_Filter(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	tests := []struct {
+		name           string
+		query          string
+		wantStatusCode int
+		wantContent    string
+	}{
+		{name: "below", query: "unter=2&s=alpha", wantStatusCode: http.StatusOK, wantContent: `value="2"`},
+		{name: "archived", query: "archiviert=on", wantStatusCode: http.StatusOK, wantContent: "Keine Ergebnisse gefunden"},
+		{name: "invalid number", query: "unter=0", wantStatusCode: http.StatusUnprocessableEntity, wantContent: validation.ErrNumberTooSmall.Error()},
+		{name: "invalid date", query: "seit=gestern", wantStatusCode: http.StatusUnprocessableEntity, wantContent: "Kein Datum"},
+		{name: "invalid photo", query: "photo=vielleicht", wantStatusCode: http.StatusUnprocessableEntity, wantContent: validation.ErrValueNotIncluded.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/dinge/?" + tt.query
+			response := testserver.Get(path)
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("GET %v = %v; want %v", path, response.StatusCode, tt.wantStatusCode)
+			}
+
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(string(body), tt.wantContent) {
+				t.Errorf("GET %v does not contain %v", path, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestIndexFormData_Url(t *testing.T) {
+	data := ding.IndexFormData{
+		Q:      "paprika",
+		S:      "alpha",
+		Filter: ding.Filter{Leer: true, Seit: must(time.Parse(time.DateOnly, "2024-11-13")), Photo: ding.MitPhoto},
+	}
+
+	tests := []struct {
+		name   string
+		q      string
+		cursor string
+		want   string
+	}{
+		{name: "first page", q: data.Q, want: "/dinge/?leer=on&photo=mit&q=paprika&s=alpha&seit=2024-11-13"},
+		{name: "next page", q: data.Q, cursor: "abc", want: "/dinge/?c=abc&leer=on&photo=mit&q=paprika&s=alpha&seit=2024-11-13"},
+		{name: "suggestion", q: "gurke", want: "/dinge/?leer=on&photo=mit&q=gurke&s=alpha&seit=2024-11-13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := data.Url(tt.q, tt.cursor); got != tt.want {
+				t.Errorf("IndexFormData.Url(%q, %q) = %v; want %v", tt.q, tt.cursor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModule_PostDingeSearches(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	tests := []struct {
+		name           string
+		data           url.Values
+		wantStatusCode int
+		wantLocation   string
+	}{
+		{name: "blank name", data: url.Values{"name": {" "}, "q": {"gurke"}}, wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "invalid filter", data: url.Values{"name": {"Leer"}, "unter": {"0"}}, wantStatusCode: http.StatusUnprocessableEntity},
+		{
+			name:           "save",
+			data:           url.Values{"name": {"Wenig Gemüse"}, "q": {"gurke"}, "s": {"alpha"}, "unter": {"3"}},
+			wantStatusCode: http.StatusSeeOther,
+			wantLocation:   "/dinge/?q=gurke&s=alpha&unter=3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := testserver.Post("/dinge/searches", tt.data)
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("POST /dinge/searches = %v; want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if location := response.Header.Get("Location"); location != tt.wantLocation {
+				t.Errorf("POST /dinge/searches Location = %v; want %v", location, tt.wantLocation)
+			}
+		})
+	}
+
+	response := testserver.Get("/dinge/")
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<a href="/dinge/?q=gurke&s=alpha&unter=3">Wenig Gemüse</a>`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("GET /dinge/ does not contain %v", want)
+	}
+
+	for _, tt := range []struct {
+		id             string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{id: "1", wantStatusCode: http.StatusOK, wantBody: "id,code,name,anzahl\n2,222,Gurke,2\n"},
+		{id: "42", wantStatusCode: http.StatusNotFound},
+		{id: "x", wantStatusCode: http.StatusNotFound},
+	} {
+		response := testserver.Get("/dinge/searches/export?id=" + tt.id)
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.StatusCode != tt.wantStatusCode {
+			t.Errorf("GET /dinge/searches/export id=%v = %v; want %v", tt.id, response.StatusCode, tt.wantStatusCode)
+		}
+
+		if tt.wantBody == "" {
+			continue
+		}
+
+		if contentType := response.Header.Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+			t.Errorf("GET /dinge/searches/export Content-Type = %v; want text/csv", contentType)
+		}
+
+		if disposition := response.Header.Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment;") {
+			t.Errorf("GET /dinge/searches/export Content-Disposition = %v; want attachment", disposition)
+		}
+
+		if string(body) != tt.wantBody {
+			t.Errorf("GET /dinge/searches/export body = %q; want %q", body, tt.wantBody)
+		}
+	}
+
+	for _, tt := range []struct {
+		id             string
+		wantStatusCode int
+	}{
+		{id: "1", wantStatusCode: http.StatusSeeOther},
+		{id: "1", wantStatusCode: http.StatusNotFound},
+		{id: "x", wantStatusCode: http.StatusNotFound},
+	} {
+		response := testserver.Post("/dinge/searches/delete", url.Values{"id": {tt.id}})
+		response.Body.Close()
+		if response.StatusCode != tt.wantStatusCode {
+			t.Errorf("POST /dinge/searches/delete id=%v = %v; want %v", tt.id, response.StatusCode, tt.wantStatusCode)
+		}
+	}
+}
+
+func TestModule_GetDingeCompletions(t *testing.T) {
+	config := newTestConfig()
+	testserver := webx.NewTestserver(t, "/dinge", config)
+	defer testserver.Close()
+
+	response := testserver.Get("/dinge/completions?q=gur")
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("GET /dinge/completions = %v; want %v", response.StatusCode, http.StatusOK)
+	}
+
+	if got := response.Header.Get("Cache-Control"); got != "private, max-age=60" {
+		t.Errorf("GET /dinge/completions Cache-Control = %v; want %v", got, "private, max-age=60")
+	}
+
+	var got []ding.Completion
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ding.Completion{{Name: "Gurke", Code: "222"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("GET /dinge/completions = %v; want %v", got, want)
+	}
+}
+
+func TestModule_Roles(t *testing.T) {
+	tests := []struct {
+		role           webx.Role
+		method         string
+		path           string
+		wantStatusCode int
+	}{
+		{role: webx.RoleViewer, method: http.MethodGet, path: "/dinge/", wantStatusCode: http.StatusOK},
+		{role: webx.RoleViewer, method: http.MethodGet, path: "/dinge/1", wantStatusCode: http.StatusOK},
+		{role: webx.RoleViewer, method: http.MethodPost, path: "/dinge/", wantStatusCode: http.StatusForbidden},
+		{role: webx.RoleViewer, method: http.MethodPost, path: "/dinge/delete", wantStatusCode: http.StatusForbidden},
+		{role: webx.RoleViewer, method: http.MethodGet, path: "/dinge/searches/export?id=1", wantStatusCode: http.StatusForbidden},
+		{role: webx.RoleEditor, method: http.MethodGet, path: "/dinge/searches/export?id=1", wantStatusCode: http.StatusForbidden},
+		{role: webx.RoleClerk, method: http.MethodGet, path: "/dinge/new", wantStatusCode: http.StatusOK},
+		{role: webx.RoleClerk, method: http.MethodGet, path: "/dinge/1/edit", wantStatusCode: http.StatusForbidden},
+		{role: webx.RoleEditor, method: http.MethodGet, path: "/dinge/1/edit", wantStatusCode: http.StatusOK},
+		{role: webx.RoleEditor, method: http.MethodPost, path: "/dinge/1/purge", wantStatusCode: http.StatusForbidden},
+		{role: webx.RoleAdmin, method: http.MethodGet, path: "/dinge/1/purge", wantStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v %v %v", tt.role, tt.method, tt.path), func(t *testing.T) {
+			config := newTestConfig()
+			config.Principal = webx.Principal{Id: 1, Name: tt.role.String(), Role: tt.role}
+			testserver := webx.NewTestserver(t, "/dinge", config)
+			defer testserver.Close()
+
+			var response *http.Response
+			if tt.method == http.MethodPost {
+				response = testserver.Post(tt.path, url.Values{})
+			} else {
+				response = testserver.Get(tt.path)
+			}
+			response.Body.Close()
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Errorf("%v %v as %v = %v; want %v", tt.method, tt.path, tt.role, response.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package ding
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// queryFields sind die Spalten der Volltextsuche, auf die eine Suche mit Präfix wie name: eingeschränkt werden kann.
+var queryFields = []string{"code", "name", "allgemein", "beschreibung"}
+
+// ParseQuery übersetzt eine Sucheingabe in eine FTS5 Abfrage.
+//
+// Die Sucheingabe besteht aus Begriffen, die durch Leerzeichen getrennt sind. Alle Begriffe müssen gefunden werden. Ein Begriff findet auch Wörter, die mit ihm beginnen, so dass zum Beispiel "schr" auch "Schraube" findet. Mehrere Wörter in Anführungszeichen werden als Phrase gesucht. Fehlt das schließende Anführungszeichen, findet die Phrase auch Wörter, die mit ihrem letzten Wort beginnen. Mit einem vorangestellten Feldnamen wie name: oder code: wird ein Begriff oder eine Phrase nur in diesem Feld gesucht.
+//
+// Jeder Begriff wird als FTS5 Zeichenkette maskiert. Dadurch führen Anführungszeichen, Bindestriche und andere Sonderzeichen der FTS5 Syntax nicht zu Fehlern. Ist die Sucheingabe leer oder enthält sie keine Wörter, liefert ParseQuery eine leere Zeichenkette.
+func ParseQuery(q string) string {
+	terms := []string{}
+
+	for {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+
+		field := ""
+		if i := strings.IndexByte(q, ':'); i > 0 && slices.Contains(queryFields, strings.ToLower(q[:i])) {
+			field, q = strings.ToLower(q[:i]), q[i+1:]
+		}
+
+		// Ein Begriff, der nicht mit einem Anführungszeichen endet, wird als Präfix gesucht.
+		var text string
+		prefix := true
+		if strings.HasPrefix(q, `"`) {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				text, q = q[1:], ""
+			} else {
+				text, q, prefix = q[1:end+1], q[end+2:], false
+			}
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				text, q = q, ""
+			} else {
+				text, q = q[:end], q[end:]
+			}
+		}
+
+		if !strings.ContainsFunc(text, isWordCharacter) {
+			continue
+		}
+
+		term := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+
+		if field != "" {
+			term = field + " : " + term
+		}
+
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+func isWordCharacter(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
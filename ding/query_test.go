@@ -0,0 +1,153 @@
+package ding_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: ""},
+		{query: "   ", want: ""},
+		{query: "schr", want: `"schr"*`},
+		{query: "rote schr", want: `"rote"* AND "schr"*`},
+		{query: `"rote Paprika"`, want: `"rote Paprika"`},
+		{query: `"offen`, want: `"offen"*`},
+		{query: `a"b`, want: `"a""b"*`},
+		{query: "name:pap", want: `name : "pap"*`},
+		{query: `Name:"rote Paprika"`, want: `name : "rote Paprika"`},
+		{query: "code:111 gemüse", want: `code : "111"* AND "gemüse"*`},
+		{query: "farbe:rot", want: `"farbe:rot"*`},
+		{query: `- " * ( ) AND`, want: `" * ( ) AND"*`},
+		{query: `- ( ) AND`, want: `"AND"*`},
+		{query: "-paprika", want: `"-paprika"*`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := ding.ParseQuery(tt.query); got != tt.want {
+				t.Errorf("ParseQuery(%q) = %v; want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_Page_Query(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		sort  string
+		want  []int64
+	}{
+		{name: "prefix", query: "pap", want: []int64{1}},
+		{name: "case insensitive prefix", query: "GUR", want: []int64{2}},
+		{name: "phrase", query: `"zur Familie"`, want: []int64{1}},
+		{name: "phrase does not match words in other order", query: `"Familie zur"`, want: []int64{}},
+		{name: "field filter", query: "code:22", want: []int64{2}},
+		{name: "field filter excludes other fields", query: "name:111", want: []int64{}},
+		{name: "unbalanced quote", query: `"Tom`, want: []int64{3}},
+		{name: "special characters", query: `Tomate - "(*`, want: []int64{3}},
+		{name: "only special characters", query: `- " *`, want: []int64{3, 2, 1}},
+		{name: "relevance", query: "planzengattung", sort: "relevance", want: []int64{1}},
+		{name: "relevance without query", sort: "relevance", want: []int64{3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				r := ding.Repository{Clock: system.RealClock{}, Tm: tm}
+
+				page, err := r.Page(context.Background(), 12, tt.query, ding.Filter{}, tt.sort, ding.Cursor{})
+				if err != nil {
+					t.Fatalf("Repository.Page(%q) error = %v", tt.query, err)
+				}
+
+				got := []int64{}
+				for _, ref := range page.Result {
+					got = append(got, ref.Id)
+				}
+
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Repository.Page(%q) = %v; want %v", tt.query, got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestRepository_Page_Relevance(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		r := ding.Repository{Clock: system.RealClock{}, Tm: tm}
+
+		// Die kurze Beschreibung der Gurke ist relevanter als die lange Beschreibung der Paprika.
+		if err := r.Aktualisieren(ctx, ding.Aktualisierungsanfrage{Id: 1, Name: "Paprika", Beschreibung: "Eine Pflanze, die weder mit der Gurke noch mit der Tomate verwandt ist"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.Aktualisieren(ctx, ding.Aktualisierungsanfrage{Id: 2, Name: "Salatgurke", Beschreibung: "Gurke"}); err != nil {
+			t.Fatal(err)
+		}
+
+		got := []int64{}
+		cursor := ding.Cursor{}
+		for {
+			page, err := r.Page(ctx, 1, "gurke", ding.Filter{}, "relevance", cursor)
+			if err != nil {
+				t.Fatalf("Repository.Page() error = %v", err)
+			}
+
+			for _, ref := range page.Result {
+				got = append(got, ref.Id)
+			}
+
+			if page.Next.IsZero() {
+				break
+			}
+			cursor = page.Next
+		}
+
+		if !slices.Equal(got, []int64{2, 1}) {
+			t.Errorf("Repository.Page() by relevance = %v; want %v", got, []int64{2, 1})
+		}
+	})
+}
+
+func TestRepository_Page_Highlights(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		r := ding.Repository{Clock: system.RealClock{}, Tm: tm}
+
+		if err := r.Aktualisieren(ctx, ding.Aktualisierungsanfrage{Id: 2, Name: "<b>Gurke</b>", Beschreibung: "Eine grüne Gurke"}); err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := r.Page(ctx, 12, "gurk", ding.Filter{}, "", ding.Cursor{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := ding.Highlight{
+			Name:    "&lt;b&gt;<mark>Gurke</mark>&lt;/b&gt;",
+			Code:    "222",
+			Snippet: "Eine grüne <mark>Gurke</mark>",
+		}
+
+		if got := page.Highlights[2]; got != want {
+			t.Errorf("Repository.Page().Highlights[2] = %v; want %v", got, want)
+		}
+
+		page, err = r.Page(ctx, 12, "", ding.Filter{}, "", ding.Cursor{})
+		if err != nil || len(page.Highlights) != 0 {
+			t.Errorf("Repository.Page() without query Highlights = %v, %v; want none", page.Highlights, err)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/haschi/dinge/sqlx"
 )
@@ -21,7 +22,7 @@ func (r Repository) GetById(ctx context.Context, id int64) (Ding, error) {
 	}
 
 	suchen := `
-	SELECT id, name, code, anzahl, beschreibung, aktualisiert, allgemein, ('/photos/' || id) AS PhotoUrl
+	SELECT id, name, code, anzahl, beschreibung, aktualisiert, allgemein, ('/photos/' || id) AS PhotoUrl, archiviert
 	FROM dinge
 	WHERE id = ?
 	`
@@ -43,13 +44,63 @@ func (r Repository) GetById(ctx context.Context, id int64) (Ding, error) {
 		&ding.Beschreibung,
 		&ding.Aktualisiert,
 		&ding.Allgemein,
-		&ding.PhotoUrl); err != nil {
+		&ding.PhotoUrl,
+		&ding.Archiviert); err != nil {
 		return ding, err
 	}
 
 	return ding, tx.Commit()
 }
 
+// GetRefs liefert die Dinge mit den Ids ids, zugeordnet zu ihrer Id.
+//
+// Ids, zu denen kein Ding existiert oder deren Ding im Papierkorb liegt, fehlen im Ergebnis.
+func (r Repository) GetRefs(ctx context.Context, ids []int64) (map[int64]DingRef, error) {
+	refs := map[int64]DingRef{}
+	if len(ids) == 0 {
+		return refs, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	suchen := fmt.Sprintf(`
+	SELECT id, name, code, anzahl, ('/photos/' || id) AS PhotoUrl
+	FROM dinge
+	WHERE id IN (%v) AND archiviert IS NULL
+	`, strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "))
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return refs, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(suchen, args...)
+	if err != nil {
+		return refs, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ref DingRef
+		if err := rows.Scan(&ref.Id, &ref.Name, &ref.Code, &ref.Anzahl, &ref.PhotoUrl); err != nil {
+			return refs, err
+		}
+		refs[ref.Id] = ref
+	}
+
+	if err := rows.Err(); err != nil {
+		return refs, err
+	}
+
+	return refs, tx.Commit()
+}
+
 // Todo: Wird nur von Destroy verwendet. Also Spezialisieren!!
 func (r Repository) MengeAktualisieren(ctx context.Context, code string, menge int) (*Ding, error) {
 
@@ -65,10 +116,12 @@ func (r Repository) MengeAktualisieren(ctx context.Context, code string, menge i
 
 	defer tx.Rollback()
 
+	code = canonicalCode(tx, code)
+
 	statement := `UPDATE dinge
 	SET anzahl = anzahl + :anzahl,
 	    aktualisiert = :aktualisiert
-	WHERE code = :code
+	WHERE code = :code AND archiviert IS NULL
 	RETURNING id, code, name, anzahl, aktualisiert`
 
 	row := tx.QueryRowContext(statement,
@@ -111,6 +164,21 @@ func (r Repository) Insert(ctx context.Context, code string, anzahl int) (Insert
 
 	defer tx.Rollback()
 
+	// Der Code eines zusammengeführten Dings bezeichnet das Ding, in das es überführt wurde.
+	code = canonicalCode(tx, code)
+
+	// Wird ein Ding aus dem Papierkorb erneut eingelagert, wird es zuerst wiederhergestellt.
+	archived, err := archivedId(tx, code)
+	if err != nil {
+		return result, err
+	}
+
+	if archived != 0 {
+		if err := r.Wiederherstellen(ctx, archived); err != nil {
+			return result, err
+		}
+	}
+
 	statement := `INSERT INTO dinge(name, code, anzahl, beschreibung, allgemein, aktualisiert)
 			VALUES(:name, :code, :anzahl, :beschreibung, :allgemein, :aktualisiert)
 			ON CONFLICT (code)
@@ -223,60 +291,6 @@ func (r Repository) Aktualisieren(ctx context.Context, anfrage Aktualisierungsan
 	return tx.Commit()
 }
 
-// TODO: Iterator statt Slice zurückgeben.
-func (r Repository) Search(ctx context.Context, limit int, query string, sort string) ([]DingRef, error) {
-
-	q := `
-		SELECT id, name, code, anzahl, ('/photos/' || id) AS PhotoUrl
-			FROM dinge
-			WHERE
-			  CASE
-				  WHEN :query <> ''
-					  THEN id IN (SELECT rowid FROM fulltext WHERE fulltext MATCH :query)
-						ELSE TRUE
-				END
-			ORDER BY
-				CASE WHEN :sort = 'alpha' THEN name END,
-				CASE WHEN :sort = 'omega' THEN name END DESC,
-				CASE WHEN :sort = 'oldest' THEN aktualisiert END,
-				CASE WHEN :sort = 'latest' THEN aktualisiert END DESC,
-				CASE WHEN :sort = '' THEN aktualisiert END DESC
-			LIMIT :limit
-		`
-
-	tx, err := r.Tm.BeginTx(ctx)
-	if err != nil {
-		return []DingRef{}, err
-	}
-
-	defer tx.Rollback()
-
-	rows, err := tx.QueryContext(q,
-		sql.Named("limit", limit),
-		sql.Named("query", query),
-		sql.Named("sort", sort))
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	var dinge []DingRef = []DingRef{}
-
-	for rows.Next() {
-		var ding DingRef
-		err = rows.Scan(&ding.Id, &ding.Name, &ding.Code, &ding.Anzahl, &ding.PhotoUrl)
-		if err != nil {
-			return dinge, err
-		}
-
-		dinge = append(dinge, ding)
-	}
-
-	return dinge, tx.Commit()
-}
-
 // ErrDataAccess beschreibt einen Fehler während des Zugriffs auf die Daten des Repositories.
 //
 // Es handelt sich dabei um einen nicht näher bezeichneten technischen Fehler. Üblicherweise verursachen [context.Canceled] oder [sql.ErrConnDone], [sql.ErrNoRows] oder [sql.ErrTxDone] diesen Fehler.
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"iter"
+	"maps"
 	"reflect"
 	"slices"
 	"testing"
@@ -87,6 +88,35 @@ func TestRepository_GetById(t *testing.T) {
 	}
 }
 
+func TestRepository_GetRefs(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		r := ding.Repository{Clock: FixedClock{Timestamp: time.Now()}, Tm: tm}
+
+		if err := r.Archivieren(ctx, dinge[1].Id); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.GetRefs(ctx, []int64{dinge[0].Id, dinge[1].Id, 42})
+		if err != nil {
+			t.Fatalf("Repository.GetRefs() error = %v", err)
+		}
+
+		want := map[int64]ding.DingRef{dinge[0].Id: dinge[0].DingRef}
+		if !maps.Equal(got, want) {
+			t.Errorf("Repository.GetRefs() = %v; want %v", got, want)
+		}
+
+		if got, err := r.GetRefs(ctx, []int64{}); err != nil || len(got) != 0 {
+			t.Errorf("Repository.GetRefs() = %v, %v; want empty result", got, err)
+		}
+
+		if tm.Count() != 0 {
+			t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+		}
+	})
+}
+
 func TestRepository_MengeAktualisieren(t *testing.T) {
 
 	type args struct {
@@ -454,13 +484,13 @@ func TestRepository_GetLatest(t *testing.T) {
 					Tm:    tm,
 				}
 
-				got, err := r.Search(context.Background(), tt.args.limit, tt.args.query, tt.args.sort)
+				got, err := r.Page(context.Background(), tt.args.limit, tt.args.query, ding.Filter{}, tt.args.sort, ding.Cursor{})
 				if (err != nil) != tt.wantErr {
 					t.Errorf("Repository.GetLatest() error = %v, wantErr %v", err, tt.wantErr)
 					return
 				}
-				if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
-					t.Errorf("Repository.GetLatest() = %v, want %v", got, tt.want)
+				if !tt.wantErr && !reflect.DeepEqual(got.Result, tt.want) {
+					t.Errorf("Repository.GetLatest() = %v, want %v", got.Result, tt.want)
 				}
 			})
 		})
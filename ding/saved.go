@@ -0,0 +1,232 @@
+package ding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+)
+
+// SavedSearch ist eine Suche der Übersicht, die unter einem Namen gespeichert ist.
+type SavedSearch struct {
+	Id     int64
+	Name   string
+	Q      string
+	S      string
+	Filter Filter
+}
+
+// Url liefert die Adresse der Übersicht, die das Ergebnis der gespeicherten Suche zeigt.
+func (s SavedSearch) Url() string {
+	return searchUrl(s.Q, s.S, s.Filter, "")
+}
+
+// searchUrl liefert die Adresse der Übersicht mit der Sucheingabe q, der Sortierung sort und dem Filter an der Position cursor.
+func searchUrl(q string, sort string, filter Filter, cursor string) string {
+	values := filter.Values()
+	values.Set("q", q)
+	values.Set("s", sort)
+	if cursor != "" {
+		values.Set("c", cursor)
+	}
+
+	return "/dinge/?" + values.Encode()
+}
+
+// SaveSearch speichert die Suche search unter ihrem Namen.
+//
+// Gibt es bereits eine Suche mit dem Namen, wird sie ersetzt. Das Ergebnis ist die Id der gespeicherten Suche.
+func (r Repository) SaveSearch(ctx context.Context, search SavedSearch) (int64, error) {
+	if ctx == nil {
+		return 0, errors.New("no context provided")
+	}
+
+	if _, _, err := search.Filter.where(); err != nil {
+		return 0, err
+	}
+
+	if _, ok := sortOrders[search.S]; !ok {
+		return 0, fmt.Errorf("unknown sort order %q: %w", search.S, ErrInvalidParameter)
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	statement := `
+	INSERT INTO saved_searches(name, q, s, leer, unter, seit, bis, photo, archiviert)
+	VALUES(:name, :q, :s, :leer, :unter, :seit, :bis, :photo, :archiviert)
+	ON CONFLICT(name) DO UPDATE SET
+	  q = excluded.q,
+	  s = excluded.s,
+	  leer = excluded.leer,
+	  unter = excluded.unter,
+	  seit = excluded.seit,
+	  bis = excluded.bis,
+	  photo = excluded.photo,
+	  archiviert = excluded.archiviert
+	RETURNING id`
+
+	var id int64
+	err = tx.QueryRowContext(statement,
+		sql.Named("name", strings.TrimSpace(search.Name)),
+		sql.Named("q", search.Q),
+		sql.Named("s", search.S),
+		sql.Named("leer", search.Filter.Leer),
+		sql.Named("unter", search.Filter.Unter),
+		sql.Named("seit", nullTime(search.Filter.Seit)),
+		sql.Named("bis", nullTime(search.Filter.Bis)),
+		sql.Named("photo", search.Filter.Photo),
+		sql.Named("archiviert", search.Filter.Archiviert)).Scan(&id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// SavedSearches liefert alle gespeicherten Suchen, sortiert nach ihrem Namen.
+func (r Repository) SavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	searches := []SavedSearch{}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return searches, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`
+	SELECT id, name, q, s, leer, unter, seit, bis, photo, archiviert
+	FROM saved_searches
+	ORDER BY name`)
+
+	if err != nil {
+		return searches, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return searches, err
+		}
+
+		searches = append(searches, search)
+	}
+
+	if err := rows.Err(); err != nil {
+		return searches, err
+	}
+
+	return searches, tx.Commit()
+}
+
+// GetSavedSearch liefert die gespeicherte Suche id. Gibt es die Suche nicht, liefert GetSavedSearch [ErrNoRecord].
+func (r Repository) GetSavedSearch(ctx context.Context, id int64) (SavedSearch, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(`
+	SELECT id, name, q, s, leer, unter, seit, bis, photo, archiviert
+	FROM saved_searches
+	WHERE id = :id`, sql.Named("id", id))
+
+	search, err := scanSavedSearch(row)
+	if err != nil {
+		return search, notFound(err)
+	}
+
+	return search, tx.Commit()
+}
+
+// DeleteSavedSearch löscht die gespeicherte Suche id. Gibt es die Suche nicht, liefert DeleteSavedSearch [ErrNoRecord].
+func (r Repository) DeleteSavedSearch(ctx context.Context, id int64) error {
+	if ctx == nil {
+		return errors.New("no context provided")
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(`DELETE FROM saved_searches WHERE id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoRecord
+	}
+
+	return tx.Commit()
+}
+
+// SearchSaved liefert alle Dinge im Ergebnis der gespeicherten Suche id.
+//
+// Wie [Repository.Search] liest SearchSaved die Dinge erst während der Iteration, so dass eine gespeicherte Suche als Quelle für Exporte oder Benachrichtigungen dienen kann.
+func (r Repository) SearchSaved(ctx context.Context, id int64) iter.Seq2[DingRef, error] {
+	return func(yield func(DingRef, error) bool) {
+		search, err := r.GetSavedSearch(ctx, id)
+		if err != nil {
+			yield(DingRef{}, err)
+			return
+		}
+
+		for ref, err := range r.Search(ctx, search.Q, search.Filter, search.S, Cursor{}) {
+			if !yield(ref, err) {
+				return
+			}
+		}
+	}
+}
+
+// scanner ist [sql.Row] oder [sql.Rows].
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSavedSearch(row scanner) (SavedSearch, error) {
+	var search SavedSearch
+	var seit, bis sql.NullTime
+	err := row.Scan(
+		&search.Id,
+		&search.Name,
+		&search.Q,
+		&search.S,
+		&search.Filter.Leer,
+		&search.Filter.Unter,
+		&seit,
+		&bis,
+		&search.Filter.Photo,
+		&search.Filter.Archiviert)
+
+	search.Filter.Seit = seit.Time
+	search.Filter.Bis = bis.Time
+	return search, err
+}
+
+// nullTime liefert NULL für den Zeitpunkt Null.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,85 @@
+package ding_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+)
+
+func TestRepository_SaveSearch(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		r := ding.Repository{Clock: FixedClock{}, Tm: tm}
+
+		search := ding.SavedSearch{
+			Name:   "Gemüse",
+			Q:      "gurke",
+			S:      "alpha",
+			Filter: ding.Filter{Unter: 3, Seit: must(time.Parse(time.DateOnly, "2024-11-13"))},
+		}
+
+		id, err := r.SaveSearch(ctx, search)
+		if err != nil {
+			t.Fatalf("Repository.SaveSearch() error = %v", err)
+		}
+
+		got, err := r.GetSavedSearch(ctx, id)
+		search.Id = id
+		if err != nil || got.Name != search.Name || got.Q != search.Q || got.S != search.S || got.Filter.Unter != 3 || !got.Filter.Seit.Equal(search.Filter.Seit) || !got.Filter.Bis.IsZero() {
+			t.Errorf("Repository.GetSavedSearch() = %v, %v; want %v", got, err, search)
+		}
+
+		// Eine Suche mit gleichem Namen ersetzt die gespeicherte Suche.
+		search.Q = "paprika"
+		if replaced, err := r.SaveSearch(ctx, search); err != nil || replaced != id {
+			t.Errorf("Repository.SaveSearch() replacing = %v, %v; want %v", replaced, err, id)
+		}
+
+		if _, err := r.SaveSearch(ctx, ding.SavedSearch{Name: "Alles"}); err != nil {
+			t.Fatalf("Repository.SaveSearch() error = %v", err)
+		}
+
+		if _, err := r.SaveSearch(ctx, ding.SavedSearch{Name: "Falsch", S: "unknown"}); !errors.Is(err, ding.ErrInvalidParameter) {
+			t.Errorf("Repository.SaveSearch() with unknown sort error = %v; want %v", err, ding.ErrInvalidParameter)
+		}
+
+		searches, err := r.SavedSearches(ctx)
+		names := []string{}
+		for _, s := range searches {
+			names = append(names, s.Name)
+		}
+
+		if err != nil || !slices.Equal(names, []string{"Alles", "Gemüse"}) {
+			t.Errorf("Repository.SavedSearches() = %v, %v; want %v", names, err, []string{"Alles", "Gemüse"})
+		}
+
+		found := []int64{}
+		for ref, err := range r.SearchSaved(ctx, id) {
+			if err != nil {
+				t.Fatalf("Repository.SearchSaved() error = %v", err)
+			}
+			found = append(found, ref.Id)
+		}
+
+		if !slices.Equal(found, []int64{1}) {
+			t.Errorf("Repository.SearchSaved() = %v; want %v", found, []int64{1})
+		}
+
+		if err := r.DeleteSavedSearch(ctx, id); err != nil {
+			t.Fatalf("Repository.DeleteSavedSearch() error = %v", err)
+		}
+
+		if err := r.DeleteSavedSearch(ctx, id); !errors.Is(err, ding.ErrNoRecord) {
+			t.Errorf("Repository.DeleteSavedSearch() repeated error = %v; want %v", err, ding.ErrNoRecord)
+		}
+
+		if _, err := r.GetSavedSearch(ctx, id); !errors.Is(err, ding.ErrNoRecord) {
+			t.Errorf("Repository.GetSavedSearch() deleted error = %v; want %v", err, ding.ErrNoRecord)
+		}
+	})
+}
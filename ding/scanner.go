@@ -16,7 +16,7 @@ type ScannerFormData struct {
 
 func NewScannerFormData(code string, anzahl int, history []Event) webx.TemplateData[ScannerFormData] {
 	return webx.TemplateData[ScannerFormData]{
-		Scripts: []string{"/static/barcode.js"},
+		Scripts: []string{"/static/barcode.js", "/static/completion.js"},
 		Styles:  []string{"/static/css/barcode.css"},
 		FormValues: ScannerFormData{
 			Title:            "Einlagern",
@@ -31,7 +31,7 @@ func NewScannerFormData(code string, anzahl int, history []Event) webx.TemplateD
 
 func NewDestroyFormData(code string, anzahl int, history []Event) webx.TemplateData[ScannerFormData] {
 	return webx.TemplateData[ScannerFormData]{
-		Scripts: []string{"/static/barcode.js"},
+		Scripts: []string{"/static/barcode.js", "/static/completion.js"},
 		Styles:  []string{"/static/css/barcode.css"},
 		FormValues: ScannerFormData{
 			Title:            "Entnehmen",
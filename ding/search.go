@@ -0,0 +1,307 @@
+package ding
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sortOrder beschreibt, wie ein Suchergebnis sortiert wird.
+type sortOrder struct {
+	column    string
+	ascending bool
+
+	// numeric ist wahr, wenn der Sortierschlüssel eine Zahl ist.
+	numeric bool
+}
+
+// sortOrders enthält die zulässigen Sortierungen eines Suchergebnisses. Ohne Angabe werden die zuletzt geänderten Dinge zuerst geliefert.
+var sortOrders = map[string]sortOrder{
+	"":          {column: "aktualisiert", ascending: false},
+	"latest":    {column: "aktualisiert", ascending: false},
+	"oldest":    {column: "aktualisiert", ascending: true},
+	"alpha":     {column: "name", ascending: true},
+	"omega":     {column: "name", ascending: false},
+	"relevance": {column: "score", ascending: true, numeric: true},
+}
+
+// Highlight enthält die Fundstellen eines Suchbegriffs in einem Ding.
+//
+// Die Texte sind HTML maskiert. Die Fundstellen sind mit dem HTML Element mark hervorgehoben.
+type Highlight struct {
+	Name    string
+	Code    string
+	Snippet string
+}
+
+// Markierungen, mit denen die Volltextsuche Fundstellen kennzeichnet, bevor sie durch HTML ersetzt werden.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+var markup = strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>")
+
+// highlight maskiert text für HTML und ersetzt die Markierungen der Volltextsuche durch das HTML Element mark.
+func highlight(text string) string {
+	return markup.Replace(html.EscapeString(text))
+}
+
+// searchResult ist ein Ding im Suchergebnis mit seinem Sortierschlüssel.
+type searchResult struct {
+	DingRef
+	key       string
+	highlight Highlight
+}
+
+// Search liefert alle Dinge, die zur Suchanfrage query passen und dem Filter entsprechen, in der Sortierung sort.
+//
+// Die Suchanfrage wird mit [ParseQuery] übersetzt. Findet sie kein Ding, liefert Search die Dinge, die zum Vorschlag von [Repository.Suggest] passen. Die Sortierung relevance sortiert nach der Relevanz der Fundstellen. Ohne Suchanfrage entspricht sie der voreingestellten Sortierung.
+//
+// Das Suchergebnis beginnt nach der Position cursor. Die Dinge werden erst während der Iteration aus der Datenbank gelesen, so dass auch große Suchergebnisse, zum Beispiel für einen Export, verarbeitet werden können. Die Transaktion bleibt bis zum Ende der Iteration geöffnet.
+func (r Repository) Search(ctx context.Context, query string, filter Filter, sort string, cursor Cursor) iter.Seq2[DingRef, error] {
+	return func(yield func(DingRef, error) bool) {
+		match, _, err := r.resolveQuery(ctx, query, filter)
+		if err != nil {
+			yield(DingRef{}, err)
+			return
+		}
+
+		for result, err := range r.search(ctx, match, filter, sort, cursor) {
+			if !yield(result.DingRef, err) {
+				return
+			}
+		}
+	}
+}
+
+// search liefert alle Dinge, die zur FTS5 Abfrage match passen und dem Filter entsprechen.
+func (r Repository) search(ctx context.Context, match string, filter Filter, sort string, cursor Cursor) iter.Seq2[searchResult, error] {
+	return func(yield func(searchResult, error) bool) {
+		order, ok := sortOrders[sort]
+		if !ok {
+			yield(searchResult{}, fmt.Errorf("unknown sort order %q: %w", sort, ErrInvalidParameter))
+			return
+		}
+
+		if order.numeric && match == "" {
+			order = sortOrders[""]
+		}
+
+		if !cursor.IsZero() && cursor.Sort != sort {
+			yield(searchResult{}, fmt.Errorf("cursor for sort order %q used with %q: %w", cursor.Sort, sort, ErrInvalidParameter))
+			return
+		}
+
+		var key any = cursor.Key
+		if order.numeric && !cursor.IsZero() {
+			number, err := strconv.ParseFloat(cursor.Key, 64)
+			if err != nil {
+				yield(searchResult{}, fmt.Errorf("malformed cursor key %q: %w", cursor.Key, ErrInvalidParameter))
+				return
+			}
+			key = number
+		}
+
+		// Rückwärts wird in umgekehrter Reihenfolge gelesen.
+		ascending := order.ascending != cursor.Backward
+
+		direction, comparison := "DESC", "<"
+		if ascending {
+			direction, comparison = "ASC", ">"
+		}
+
+		where, args, err := filter.where()
+		if err != nil {
+			yield(searchResult{}, err)
+			return
+		}
+
+		source := `
+		SELECT id, name, code, anzahl, aktualisiert, '' AS name_highlight, '' AS code_highlight, '' AS snippet, 0.0 AS score
+		FROM dinge
+		WHERE ` + where
+
+		if match != "" {
+			source = `
+			SELECT dinge.id, dinge.name, dinge.code, dinge.anzahl, dinge.aktualisiert,
+			  highlight(fulltext, 1, :start, :end) AS name_highlight,
+			  highlight(fulltext, 0, :start, :end) AS code_highlight,
+			  snippet(fulltext, 3, :start, :end, '…', 12) AS snippet,
+			  bm25(fulltext) AS score
+			FROM fulltext
+			INNER JOIN dinge ON dinge.id = fulltext.rowid
+			WHERE fulltext MATCH :query AND ` + where
+		}
+
+		keyColumn := fmt.Sprintf("CAST(%v AS TEXT)", order.column)
+		if order.numeric {
+			keyColumn = order.column
+		}
+
+		// Die Spalte stammt aus sortOrders und nicht aus der Anfrage.
+		q := fmt.Sprintf(`
+		SELECT id, name, code, anzahl, ('/photos/' || id) AS PhotoUrl, %[4]v, name_highlight, code_highlight, snippet
+		FROM (%[5]v)
+		WHERE CASE
+		    WHEN :id = 0 THEN TRUE
+		    ELSE %[1]v %[2]v :key OR (%[1]v = :key AND id %[2]v :id)
+		  END
+		ORDER BY %[1]v %[3]v, id %[3]v
+		`, order.column, comparison, direction, keyColumn, source)
+
+		tx, err := r.Tm.BeginTx(ctx)
+		if err != nil {
+			yield(searchResult{}, err)
+			return
+		}
+
+		defer tx.Rollback()
+
+		rows, err := tx.QueryContext(q, append(args,
+			sql.Named("query", match),
+			sql.Named("start", markStart),
+			sql.Named("end", markEnd),
+			sql.Named("key", key),
+			sql.Named("id", cursor.Id))...)
+
+		if err != nil {
+			yield(searchResult{}, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var result searchResult
+			var score float64
+			var keyDestination any = &result.key
+			if order.numeric {
+				keyDestination = &score
+			}
+
+			if err := rows.Scan(
+				&result.Id,
+				&result.Name,
+				&result.Code,
+				&result.Anzahl,
+				&result.PhotoUrl,
+				keyDestination,
+				&result.highlight.Name,
+				&result.highlight.Code,
+				&result.highlight.Snippet); err != nil {
+				yield(result, err)
+				return
+			}
+
+			if order.numeric {
+				result.key = strconv.FormatFloat(score, 'g', -1, 64)
+			}
+
+			// Ein Ausschnitt der Beschreibung ist nur interessant, wenn er eine Fundstelle enthält.
+			if !strings.Contains(result.highlight.Snippet, markStart) {
+				result.highlight.Snippet = ""
+			}
+
+			result.highlight = Highlight{
+				Name:    highlight(result.highlight.Name),
+				Code:    highlight(result.highlight.Code),
+				Snippet: highlight(result.highlight.Snippet),
+			}
+
+			if !yield(result, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(searchResult{}, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			yield(searchResult{}, err)
+		}
+	}
+}
+
+// Page ist eine Seite eines Suchergebnisses.
+type Page struct {
+	Result []DingRef
+
+	// Suggestion ist die Sucheingabe, deren Ergebnis die Seite enthält, weil die ursprüngliche Sucheingabe kein Ding gefunden hat.
+	Suggestion string
+
+	// Highlights enthält die Fundstellen der Suchanfrage zu den Ids der Dinge im Ergebnis. Ohne Suchanfrage ist Highlights leer.
+	Highlights map[int64]Highlight
+
+	// Next ist die Position der folgenden Seite. Next ist leer, wenn es keine folgende Seite gibt.
+	Next Cursor
+
+	// Previous ist die Position der vorherigen Seite. Previous ist leer, wenn es keine vorherige Seite gibt.
+	Previous Cursor
+}
+
+// Page liefert höchstens limit Dinge, die zur Suchanfrage query passen und dem Filter entsprechen, beginnend an der Position cursor.
+func (r Repository) Page(ctx context.Context, limit int, query string, filter Filter, sort string, cursor Cursor) (Page, error) {
+	page := Page{Result: []DingRef{}, Highlights: map[int64]Highlight{}}
+	limit = max(limit, 0)
+
+	match, suggestion, err := r.resolveQuery(ctx, query, filter)
+	if err != nil {
+		return page, err
+	}
+
+	page.Suggestion = suggestion
+
+	// Ein zusätzliches Ding zeigt an, ob es in Leserichtung eine weitere Seite gibt.
+	results := []searchResult{}
+	for result, err := range r.search(ctx, match, filter, sort, cursor) {
+		if err != nil {
+			return page, err
+		}
+
+		results = append(results, result)
+		if len(results) > limit {
+			break
+		}
+	}
+
+	more := len(results) > limit
+	if more {
+		results = results[:limit]
+	}
+
+	if cursor.Backward {
+		slices.Reverse(results)
+	}
+
+	for _, result := range results {
+		page.Result = append(page.Result, result.DingRef)
+		if result.highlight != (Highlight{}) {
+			page.Highlights[result.Id] = result.highlight
+		}
+	}
+
+	if len(results) == 0 {
+		return page, nil
+	}
+
+	first, last := results[0], results[len(results)-1]
+
+	// Eine Seite, die über einen Cursor erreicht wurde, hat in der Gegenrichtung stets eine Nachbarseite.
+	if more || cursor.Backward {
+		page.Next = Cursor{Sort: sort, Key: last.key, Id: last.Id}
+	}
+
+	if (more && cursor.Backward) || (!cursor.IsZero() && !cursor.Backward) {
+		page.Previous = Cursor{Sort: sort, Key: first.key, Id: first.Id, Backward: true}
+	}
+
+	return page, nil
+}
@@ -0,0 +1,146 @@
+package ding_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+)
+
+func TestRepository_Page(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []int64
+	}{
+		{sort: "", want: []int64{3, 2, 1}},
+		{sort: "latest", want: []int64{3, 2, 1}},
+		{sort: "oldest", want: []int64{1, 2, 3}},
+		{sort: "alpha", want: []int64{2, 1, 3}},
+		{sort: "omega", want: []int64{3, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				ctx := context.Background()
+				r := ding.Repository{Clock: system.RealClock{}, Tm: tm}
+
+				// Vorwärts bis zur letzten Seite blättern.
+				forward := []int64{}
+				cursors := []ding.Cursor{}
+				cursor := ding.Cursor{}
+				for {
+					page, err := r.Page(ctx, 1, "", ding.Filter{}, tt.sort, cursor)
+					if err != nil {
+						t.Fatalf("Repository.Page() error = %v", err)
+					}
+
+					for _, ref := range page.Result {
+						forward = append(forward, ref.Id)
+					}
+
+					if cursor.IsZero() != page.Previous.IsZero() {
+						t.Errorf("Repository.Page(%v).Previous = %v; want previous page only after first page", cursor, page.Previous)
+					}
+
+					cursors = append(cursors, page.Previous)
+					if page.Next.IsZero() {
+						break
+					}
+
+					cursor = page.Next
+				}
+
+				if !slices.Equal(forward, tt.want) {
+					t.Errorf("Repository.Page() forward = %v; want %v", forward, tt.want)
+				}
+
+				// Von der letzten Seite rückwärts bis zur ersten Seite blättern.
+				backward := []int64{}
+				cursor = cursors[len(cursors)-1]
+				for !cursor.IsZero() {
+					page, err := r.Page(ctx, 1, "", ding.Filter{}, tt.sort, cursor)
+					if err != nil {
+						t.Fatalf("Repository.Page() error = %v", err)
+					}
+
+					if page.Next.IsZero() {
+						t.Errorf("Repository.Page(%v).Next is empty; want next page", cursor)
+					}
+
+					for _, ref := range page.Result {
+						backward = append(backward, ref.Id)
+					}
+
+					cursor = page.Previous
+				}
+
+				slices.Reverse(backward)
+				if !slices.Equal(backward, tt.want[:len(tt.want)-1]) {
+					t.Errorf("Repository.Page() backward = %v; want %v", backward, tt.want[:len(tt.want)-1])
+				}
+
+				if tm.Count() != 0 {
+					t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+				}
+			})
+		})
+	}
+}
+
+func TestRepository_Search(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		r := ding.Repository{Clock: system.RealClock{}, Tm: tm}
+
+		got := []int64{}
+		for ref, err := range r.Search(ctx, "", ding.Filter{}, "alpha", ding.Cursor{}) {
+			if err != nil {
+				t.Fatalf("Repository.Search() error = %v", err)
+			}
+
+			got = append(got, ref.Id)
+			if len(got) == 2 {
+				break
+			}
+		}
+
+		if !slices.Equal(got, []int64{2, 1}) {
+			t.Errorf("Repository.Search() = %v; want %v", got, []int64{2, 1})
+		}
+
+		if tm.Count() != 0 {
+			t.Errorf("TransactionManager.Count() after break = %v; want %v", tm.Count(), 0)
+		}
+
+		cursor := ding.Cursor{Sort: "omega", Key: "Gurke", Id: 2}
+		for _, err := range r.Search(ctx, "", ding.Filter{}, "alpha", cursor) {
+			if !errors.Is(err, ding.ErrInvalidParameter) {
+				t.Errorf("Repository.Search() with cursor of other sort order error = %v; want %v", err, ding.ErrInvalidParameter)
+			}
+		}
+	})
+}
+
+func TestParseCursor(t *testing.T) {
+	cursor := ding.Cursor{Sort: "latest", Key: "2024-11-13 19:05:02", Id: 2, Backward: true}
+
+	got, err := ding.ParseCursor(cursor.String())
+	if err != nil || got != cursor {
+		t.Errorf("ParseCursor(%v) = %v, %v; want %v", cursor.String(), got, err, cursor)
+	}
+
+	for _, s := range []string{"#", "e30", "bm9uc2Vuc2U"} {
+		if _, err := ding.ParseCursor(s); !errors.Is(err, ding.ErrInvalidParameter) {
+			t.Errorf("ParseCursor(%q) error = %v; want %v", s, err, ding.ErrInvalidParameter)
+		}
+	}
+
+	if got, err := ding.ParseCursor(""); err != nil || !got.IsZero() {
+		t.Errorf("ParseCursor(\"\") = %v, %v; want zero cursor", got, err)
+	}
+}
@@ -1,6 +1,57 @@
 package ding
 
+import (
+	"time"
+
+	"github.com/haschi/dinge/webx"
+)
+
 type ShowResponseData struct {
 	Ding
-	History []Event
+	History    []Event
+	Lookalikes []DingRef
+	Aliases    []string
+}
+
+// HistoryResponseData enthält die Ereignisse der Historie, gefiltert nach dem Akteur Actor.
+type HistoryResponseData struct {
+	Actor  string
+	Actors []string
+	Events []Event
+}
+
+type EditResponseData struct {
+	Ding
+	Lookalikes []DingRef
+}
+
+// DuplicatePair ist ein Paar von Dingen, deren Photos sich ähnlich sehen.
+type DuplicatePair struct {
+	First  DingRef
+	Second DingRef
+}
+
+type DuplicatesResponseData struct {
+	Pairs []DuplicatePair
+}
+
+// MergeResponseData enthält das Ding, in das ein anderes Ding mit dem Produktcode OtherCode überführt werden soll.
+type MergeResponseData struct {
+	Ding
+	OtherCode string
+}
+
+// TrashItem ist ein Ding im Papierkorb.
+type TrashItem struct {
+	Ding
+
+	// Loeschdatum ist der Zeitpunkt, ab dem das Ding endgültig gelöscht wird. Der Zeitpunkt ist 0, wenn Dinge nicht automatisch gelöscht werden.
+	Loeschdatum time.Time
+}
+
+type TrashResponseData struct {
+	Items []TrashItem
+
+	// Principal ist der angemeldete Benutzer. Nur Administratoren dürfen Dinge endgültig löschen.
+	Principal webx.Principal
 }
@@ -7,5 +7,8 @@ import (
 //go:embed create.sql
 var CreateScript string
 
+//go:embed upgrade.sql
+var UpgradeScript string
+
 //go:embed fixture.sql
 var FixtureScript string
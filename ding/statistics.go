@@ -0,0 +1,69 @@
+package ding
+
+import "context"
+
+// Statistics sind Kennzahlen des Bestands.
+type Statistics struct {
+	// Dinge ist die Anzahl der Dinge außerhalb des Papierkorbs.
+	Dinge int
+
+	// Archived ist die Anzahl der Dinge im Papierkorb.
+	Archived int
+
+	// Stock ist die Summe der Anzahlen aller Dinge außerhalb des Papierkorbs.
+	Stock int
+
+	// Events ist die Anzahl der Ereignisse der Historie je Operation, zum Beispiel add oder delete.
+	Events map[string]int
+}
+
+// Statistics liefert die Kennzahlen des Bestands.
+func (r Repository) Statistics(ctx context.Context) (Statistics, error) {
+	statistics := Statistics{Events: map[string]int{}}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return statistics, err
+	}
+
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(`
+	SELECT
+	  COUNT(*) FILTER (WHERE archiviert IS NULL),
+	  COUNT(*) FILTER (WHERE archiviert IS NOT NULL),
+	  COALESCE(SUM(anzahl) FILTER (WHERE archiviert IS NULL), 0)
+	FROM dinge`)
+
+	if err := row.Scan(&statistics.Dinge, &statistics.Archived, &statistics.Stock); err != nil {
+		return statistics, err
+	}
+
+	rows, err := tx.QueryContext(`
+	SELECT operation.name, COUNT(history.operation)
+	FROM operation
+	LEFT JOIN history ON history.operation = operation.id
+	GROUP BY operation.id`)
+
+	if err != nil {
+		return statistics, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return statistics, err
+		}
+
+		statistics.Events[name] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return statistics, err
+	}
+
+	return statistics, tx.Commit()
+}
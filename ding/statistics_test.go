@@ -0,0 +1,44 @@
+package ding_test
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+)
+
+func TestRepository_Statistics(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		timestamp := must(time.Parse(time.DateTime, "2024-12-01 10:00:00"))
+		r := ding.Repository{Clock: FixedClock{Timestamp: timestamp}, Tm: tm}
+
+		if _, err := r.MengeAktualisieren(ctx, dinge[1].Code, -1); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.Archivieren(ctx, dinge[2].Id); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.Statistics(ctx)
+		if err != nil {
+			t.Fatalf("Repository.Statistics() error = %v", err)
+		}
+
+		// Paprika 1 und Gurke 2 - 1, Tomate liegt im Papierkorb.
+		want := ding.Statistics{
+			Dinge:    2,
+			Archived: 1,
+			Stock:    2,
+			Events:   map[string]int{"new": 3, "add": 0, "delete": 1, "merge": 0, "archive": 1, "restore": 0},
+		}
+
+		if got.Dinge != want.Dinge || got.Archived != want.Archived || got.Stock != want.Stock || !maps.Equal(got.Events, want.Events) {
+			t.Errorf("Repository.Statistics() = %+v; want %+v", got, want)
+		}
+	})
+}
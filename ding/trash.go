@@ -0,0 +1,203 @@
+package ding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/haschi/dinge/sqlx"
+)
+
+// Archivieren legt das Ding id in den Papierkorb.
+//
+// Dinge im Papierkorb werden bei der Suche und beim Entnehmen nicht mehr gefunden. Sie können mit [Repository.Wiederherstellen] zurückgeholt oder mit [Repository.Entfernen] endgültig gelöscht werden. Wird ein Ding im Papierkorb erneut eingelagert, wird es ebenfalls wiederhergestellt.
+func (r Repository) Archivieren(ctx context.Context, id int64) error {
+	statement := `UPDATE dinge SET archiviert = :archiviert WHERE id = :id AND archiviert IS NULL`
+	return r.changeArchive(ctx, statement, 5, id, sql.Named("archiviert", r.Clock.Now()))
+}
+
+// Wiederherstellen holt das Ding id aus dem Papierkorb zurück.
+func (r Repository) Wiederherstellen(ctx context.Context, id int64) error {
+	statement := `UPDATE dinge SET archiviert = NULL WHERE id = :id AND archiviert IS NOT NULL`
+	return r.changeArchive(ctx, statement, 6, id)
+}
+
+// archivedId liefert die Id des Dings mit dem Code code, wenn es im Papierkorb liegt, sonst 0.
+func archivedId(tx sqlx.Transaction, code string) (int64, error) {
+	suchen := `SELECT id FROM dinge WHERE code = :code AND archiviert IS NOT NULL`
+
+	var id int64
+	if err := tx.QueryRowContext(suchen, sql.Named("code", code)).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r Repository) changeArchive(ctx context.Context, statement string, operation int, id int64, args ...any) error {
+	if ctx == nil {
+		return errors.New("no context provided")
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(statement, append(args, sql.Named("id", id))...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoRecord
+	}
+
+	if err := r.LogEvent(ctx, operation, 0, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Papierkorb liefert die Dinge im Papierkorb. Die zuletzt archivierten Dinge stehen am Anfang.
+func (r Repository) Papierkorb(ctx context.Context, limit int) ([]Ding, error) {
+	dinge := []Ding{}
+
+	q := `
+	SELECT id, name, code, anzahl, beschreibung, aktualisiert, allgemein, ('/photos/' || id) AS PhotoUrl, archiviert
+	FROM dinge
+	WHERE archiviert IS NOT NULL
+	ORDER BY archiviert DESC
+	LIMIT :limit
+	`
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return dinge, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(q, sql.Named("limit", limit))
+	if err != nil {
+		return dinge, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ding Ding
+		if err := rows.Scan(
+			&ding.Id,
+			&ding.Name,
+			&ding.Code,
+			&ding.Anzahl,
+			&ding.Beschreibung,
+			&ding.Aktualisiert,
+			&ding.Allgemein,
+			&ding.PhotoUrl,
+			&ding.Archiviert); err != nil {
+			return dinge, err
+		}
+
+		dinge = append(dinge, ding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return dinge, err
+	}
+
+	return dinge, tx.Commit()
+}
+
+// Entfernen löscht das Ding id aus dem Papierkorb endgültig.
+//
+// Mit dem Ding werden seine Historie, seine Aliase und sein Eintrag in der Volltextsuche gelöscht. Das Photo des Dings entfernt die Datenbank über den Fremdschlüssel. Befindet sich das Ding nicht im Papierkorb, liefert Entfernen [ErrNoRecord].
+func (r Repository) Entfernen(ctx context.Context, id int64) error {
+	if ctx == nil {
+		return errors.New("no context provided")
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var archiviert sql.NullTime
+	row := tx.QueryRowContext(`SELECT archiviert FROM dinge WHERE id = :id`, sql.Named("id", id))
+	if err := row.Scan(&archiviert); err != nil {
+		return notFound(err)
+	}
+
+	if !archiviert.Valid {
+		return ErrNoRecord
+	}
+
+	statements := []string{
+		`DELETE FROM history WHERE dinge_id = :id`,
+		`DELETE FROM aliases WHERE dinge_id = :id`,
+		`DELETE FROM fulltext WHERE rowid = :id`,
+		`DELETE FROM dinge WHERE id = :id`,
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(statement, sql.Named("id", id)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PapierkorbLeeren löscht alle Dinge endgültig, die vor dem Zeitpunkt before in den Papierkorb gelegt wurden.
+//
+// Das Ergebnis ist die Anzahl der gelöschten Dinge.
+func (r Repository) PapierkorbLeeren(ctx context.Context, before time.Time) (int, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`SELECT id FROM dinge WHERE archiviert < :before`, sql.Named("before", before))
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := r.Entfernen(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), tx.Commit()
+}
@@ -0,0 +1,204 @@
+package ding_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+)
+
+func TestRepository_Archivieren(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		timestamp := must(time.Parse(time.DateTime, "2024-12-01 10:00:00"))
+		r := ding.Repository{Clock: FixedClock{Timestamp: timestamp}, Tm: tm}
+
+		if err := r.Archivieren(ctx, dinge[0].Id); err != nil {
+			t.Fatalf("Repository.Archivieren() error = %v", err)
+		}
+
+		if err := r.Archivieren(ctx, dinge[0].Id); !errors.Is(err, ding.ErrNoRecord) {
+			t.Errorf("Repository.Archivieren() repeated error = %v; want %v", err, ding.ErrNoRecord)
+		}
+
+		got, err := r.GetById(ctx, dinge[0].Id)
+		if err != nil || !got.Archiviert.Valid || !got.Archiviert.Time.Equal(timestamp) {
+			t.Errorf("Repository.GetById().Archiviert = %v, %v; want %v", got.Archiviert, err, timestamp)
+		}
+
+		found, err := r.Page(ctx, 12, dinge[0].Code, ding.Filter{}, "", ding.Cursor{})
+		if err != nil || len(found.Result) != 0 {
+			t.Errorf("Repository.Page() = %v, %v; want no result", found.Result, err)
+		}
+
+		if _, err := r.MengeAktualisieren(ctx, dinge[0].Code, -1); !errors.Is(err, ding.ErrNoRecord) {
+			t.Errorf("Repository.MengeAktualisieren() error = %v; want %v", err, ding.ErrNoRecord)
+		}
+
+		trash, err := r.Papierkorb(ctx, 10)
+		if err != nil || len(trash) != 1 || trash[0].Id != dinge[0].Id {
+			t.Errorf("Repository.Papierkorb() = %v, %v; want ding %v", trash, err, dinge[0].Id)
+		}
+
+		if err := r.Wiederherstellen(ctx, dinge[0].Id); err != nil {
+			t.Fatalf("Repository.Wiederherstellen() error = %v", err)
+		}
+
+		if err := r.Wiederherstellen(ctx, dinge[0].Id); !errors.Is(err, ding.ErrNoRecord) {
+			t.Errorf("Repository.Wiederherstellen() repeated error = %v; want %v", err, ding.ErrNoRecord)
+		}
+
+		found, err = r.Page(ctx, 12, dinge[0].Code, ding.Filter{}, "", ding.Cursor{})
+		if err != nil || len(found.Result) != 1 {
+			t.Errorf("Repository.Page() = %v, %v; want one result", found.Result, err)
+		}
+
+		history, err := r.ProductHistory(ctx, dinge[0].Id, 10)
+		if err != nil || len(history) != 3 {
+			t.Errorf("Repository.ProductHistory() = %v, %v; want 3 events", history, err)
+		}
+
+		if tm.Count() != 0 {
+			t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+		}
+	})
+}
+
+func TestRepository_Insert_Archived(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		r := ding.Repository{Clock: FixedClock{Timestamp: time.Now()}, Tm: tm}
+
+		if err := r.Archivieren(ctx, dinge[1].Id); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := r.Insert(ctx, dinge[1].Code, 1)
+		if err != nil || result.Id != dinge[1].Id {
+			t.Fatalf("Repository.Insert() = %v, %v; want id %v", result, err, dinge[1].Id)
+		}
+
+		got, err := r.GetById(ctx, dinge[1].Id)
+		if err != nil || got.Archiviert.Valid {
+			t.Errorf("Repository.GetById().Archiviert = %v, %v; want restored ding", got.Archiviert, err)
+		}
+
+		// Die Historie enthält die Wiederherstellung und die Einlagerung.
+		history, err := r.ProductHistory(ctx, dinge[1].Id, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		operations := []int{}
+		for _, event := range history {
+			operations = append(operations, event.Operation)
+		}
+
+		if !slices.Contains(operations, 6) || !slices.Contains(operations, 2) {
+			t.Errorf("Repository.ProductHistory() operations = %v; want restore (6) and add (2)", operations)
+		}
+
+		if tm.Count() != 0 {
+			t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+		}
+	})
+}
+
+func TestRepository_Entfernen(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive bool
+		id      int64
+		wantErr error
+	}{
+		{name: "remove archived ding", archive: true, id: 1},
+		{name: "remove ding not in trash", id: 1, wantErr: ding.ErrNoRecord},
+		{name: "remove unknown ding", id: 42, wantErr: ding.ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				ctx := context.Background()
+				r := ding.Repository{Clock: FixedClock{Timestamp: time.Now()}, Tm: tm}
+
+				// Das Ding hat einen Alias, der ebenfalls entfernt wird.
+				if err := r.Merge(ctx, 1, 2); err != nil {
+					t.Fatal(err)
+				}
+
+				if tt.archive {
+					if err := r.Archivieren(ctx, tt.id); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				err := r.Entfernen(ctx, tt.id)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Repository.Entfernen() error = %v; want %v", err, tt.wantErr)
+				}
+
+				if err != nil {
+					return
+				}
+
+				if _, err := r.GetById(ctx, tt.id); err == nil {
+					t.Errorf("Repository.GetById() should fail after Entfernen()")
+				}
+
+				history, err := r.ProductHistory(ctx, tt.id, 10)
+				if err != nil || len(history) != 0 {
+					t.Errorf("Repository.ProductHistory() = %v, %v; want no events", history, err)
+				}
+
+				aliases, err := r.Aliases(ctx, tt.id)
+				if err != nil || len(aliases) != 0 {
+					t.Errorf("Repository.Aliases() = %v, %v; want no aliases", aliases, err)
+				}
+
+				// Code und Alias sind frei und werden als neue Dinge erfasst.
+				for _, code := range []string{dinge[0].Code, dinge[1].Code} {
+					result, err := r.Insert(ctx, code, 1)
+					if err != nil || !result.Created {
+						t.Errorf("Repository.Insert(%v) = %v, %v; want new ding", code, result, err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestRepository_PapierkorbLeeren(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		clock := &FixedClock{Timestamp: must(time.Parse(time.DateTime, "2024-12-01 10:00:00"))}
+		r := ding.Repository{Clock: clock, Tm: tm}
+
+		if err := r.Archivieren(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		clock.Timestamp = clock.Timestamp.Add(48 * time.Hour)
+		if err := r.Archivieren(ctx, 2); err != nil {
+			t.Fatal(err)
+		}
+
+		removed, err := r.PapierkorbLeeren(ctx, clock.Timestamp.Add(-24*time.Hour))
+		if err != nil || removed != 1 {
+			t.Fatalf("Repository.PapierkorbLeeren() = %v, %v; want 1, nil", removed, err)
+		}
+
+		trash, err := r.Papierkorb(ctx, 10)
+		if err != nil || len(trash) != 1 || trash[0].Id != 2 {
+			t.Errorf("Repository.Papierkorb() = %v, %v; want ding 2", trash, err)
+		}
+
+		if tm.Count() != 0 {
+			t.Errorf("TransactionManager.Count() = %v; want %v", tm.Count(), 0)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+-- Aktualisiert eine Datenbank, die mit einer früheren Version von create.sql angelegt wurde.
+--
+-- Das Skript darf nur einmal ausgeführt werden. Mit sqlite3 -bail bleibt die Datenbank unverändert, wenn eine Anweisung fehlschlägt.
+BEGIN;
+
+-- Papierkorb. Siehe Repository.Archivieren
+ALTER TABLE dinge ADD COLUMN archiviert DATETIME;
+
+-- Die Volltextsuche erhält einen Tokenizer, der Umlaute und Vokale gleich behandelt. Die Einträge werden dazu neu indiziert.
+CREATE TEMPORARY TABLE fulltext_upgrade AS
+SELECT rowid, code, name, allgemein, beschreibung
+FROM fulltext;
+
+DROP TABLE fulltext;
+
+CREATE VIRTUAL TABLE fulltext USING fts5(
+  code,
+  name,
+  allgemein,
+  beschreibung,
+  tokenize = "unicode61 remove_diacritics 2"
+);
+
+INSERT INTO fulltext(rowid, code, name, allgemein, beschreibung)
+SELECT rowid, code, name, allgemein, beschreibung
+FROM fulltext_upgrade;
+
+DROP TABLE fulltext_upgrade;
+
+CREATE VIRTUAL TABLE fulltext_vocab USING fts5vocab(fulltext, row);
+
+-- Bisherige Einträge der Historie haben keinen bekannten Benutzer.
+ALTER TABLE history ADD COLUMN actor VARCHAR(100) NOT NULL DEFAULT '';
+CREATE INDEX idx_history_actor ON history(actor);
+
+INSERT INTO operation(id, name)
+VALUES (4, "merge"),
+  (5, "archive"),
+  (6, "restore");
+
+CREATE TABLE aliases(
+  code VARCHAR(100) NOT NULL PRIMARY KEY,
+  dinge_id INTEGER NOT NULL REFERENCES dinge
+);
+CREATE INDEX idx_aliases_dingeId ON aliases(dinge_id);
+
+CREATE TABLE saved_searches(
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(100) NOT NULL,
+  q VARCHAR(100) NOT NULL,
+  s VARCHAR(20) NOT NULL,
+  leer BOOLEAN NOT NULL,
+  unter INTEGER NOT NULL,
+  seit DATETIME,
+  bis DATETIME,
+  photo VARCHAR(10) NOT NULL,
+  archiviert BOOLEAN NOT NULL
+);
+CREATE UNIQUE INDEX idx_saved_searches_name ON saved_searches(name);
+
+COMMIT;
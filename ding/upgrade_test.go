@@ -0,0 +1,131 @@
+package ding_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/testx"
+)
+
+// legacyScript legt die Tabellen an, wie sie vor der Einführung von ding/upgrade.sql angelegt wurden.
+const legacyScript = `
+CREATE TABLE dinge(
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  code VARCHAR(100) NOT NULL,
+  name TEXT NOT NULL,
+  anzahl INTEGER NOT NULL,
+  beschreibung TEXT NOT NULL,
+  allgemein TEXT NOT NULL,
+  aktualisiert DATETIME NOT NULL
+);
+CREATE INDEX idx_dinge_aktualisiert ON dinge(aktualisiert);
+CREATE UNIQUE INDEX idx_dinge_code ON dinge(code);
+CREATE VIRTUAL TABLE fulltext USING fts5(code, name, allgemein, beschreibung);
+CREATE TABLE history(
+  operation INTEGER NOT NULL REFERENCES operation,
+  count INTEGER NOT NULL,
+  created DATETIME NOT NULL,
+  dinge_id INTEGER NOT NULL REFERENCES dinge
+);
+CREATE INDEX idx_history_created ON history(created);
+CREATE INDEX idx_history_dingeId ON history(dinge_id);
+CREATE TABLE operation(
+  id INTEGER NOT NULL PRIMARY KEY,
+  name TEXT NOT NULL
+);
+INSERT INTO operation(id, name)
+VALUES(1, 'new'),
+  (2, "add"),
+  (3, "delete");
+`
+
+const legacyFixture = `
+INSERT INTO dinge(name, code, anzahl, beschreibung, allgemein, aktualisiert)
+VALUES ('Schlüssel', '111', 1, '', 'Haushalt', '2024-11-13 18:48:01');
+
+INSERT INTO fulltext(rowid, code, name, allgemein, beschreibung)
+SELECT id, code, name, allgemein, beschreibung
+FROM dinge;
+
+INSERT INTO history(operation, count, created, dinge_id)
+VALUES (1, 1, '2024-11-13 18:48:01', 1);
+`
+
+func TestUpgradeScript(t *testing.T) {
+	want := schemaOf(t, ding.CreateScript)
+
+	db, err := sqlx.NewTestDatabase(legacyScript, legacyFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+	db.SetMaxOpenConns(0)
+
+	if _, err := db.Exec(ding.UpgradeScript); err != nil {
+		t.Fatalf("upgrade error = %v", err)
+	}
+
+	got, err := testx.Schema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("schema after upgrade = %v; want %v", got, want)
+	}
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	r := ding.Repository{Clock: FixedClock{Timestamp: must(time.Parse(time.DateTime, "2024-12-01 10:00:00"))}, Tm: tm}
+
+	// Der neue Tokenizer findet bestehende Dinge auch ohne Umlaute.
+	page, err := r.Page(ctx, 12, "schlussel", ding.Filter{}, "", ding.Cursor{})
+	if err != nil || len(page.Result) != 1 {
+		t.Errorf("Repository.Page() = %v, %v; want one result", page.Result, err)
+	}
+
+	if _, err := r.Insert(ctx, "111", 1); err != nil {
+		t.Errorf("Repository.Insert() error = %v", err)
+	}
+
+	if err := r.Archivieren(ctx, 1); err != nil {
+		t.Errorf("Repository.Archivieren() error = %v", err)
+	}
+
+	if _, err := r.SaveSearch(ctx, ding.SavedSearch{Name: "Haushalt", Q: "haushalt"}); err != nil {
+		t.Errorf("Repository.SaveSearch() error = %v", err)
+	}
+
+	history, err := r.ProductHistory(ctx, 1, 10)
+	if err != nil || len(history) != 3 {
+		t.Errorf("Repository.ProductHistory() = %v, %v; want 3 events", history, err)
+	}
+}
+
+// schemaOf liefert das Schema einer Datenbank, die mit scripts angelegt wurde.
+func schemaOf(t *testing.T, scripts ...string) []string {
+	t.Helper()
+
+	db, err := sqlx.NewTestDatabase(scripts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	schema, err := testx.Schema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
@@ -3,6 +3,11 @@ module github.com/haschi/dinge
 go 1.23.2
 
 require (
+	github.com/makiuchi-d/gozxing v0.1.1
 	github.com/mattn/go-sqlite3 v1.14.24
+	golang.org/x/crypto v0.29.0
 	golang.org/x/net v0.31.0
+	golang.org/x/text v0.20.0
 )
+
+require golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
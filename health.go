@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/haschi/dinge/webx"
+)
+
+// requiredTables sind die Tabellen, die die Module in der Datenbank erwarten.
+var requiredTables = []string{
+	"dinge", "history", "operation", "aliases", "saved_searches",
+	"photos", "photo_garbage",
+	"users", "sessions", "api_tokens",
+}
+
+// healthResponseData ist das Ergebnis von /healthz und /readyz.
+type healthResponseData struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// health beantwortet die Anfragen eines Reverse Proxys oder Monitorings, ob der Server läuft und Anfragen bearbeiten kann.
+type health struct {
+	DB *sql.DB
+
+	// Dir ist das Verzeichnis der Datenbank, in das SQLite das Write-Ahead-Log schreibt. Leer für eine Datenbank im Speicher.
+	Dir string
+
+	shuttingDown atomic.Bool
+}
+
+// ShutDown meldet den Server als nicht mehr bereit, damit ein Reverse Proxy keine neuen Anfragen mehr sendet.
+func (h *health) ShutDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Live antwortet immer mit 200 OK, solange der Prozess Anfragen beantwortet.
+func (h *health) Live(w http.ResponseWriter, r *http.Request) {
+	h.render(w, healthResponseData{Status: "ok"}, http.StatusOK)
+}
+
+// Ready antwortet mit 200 OK, wenn die Datenbank erreichbar ist, alle Tabellen enthält und ihr Verzeichnis beschreibbar ist.
+//
+// Sobald der Server herunterfährt, antwortet Ready mit 503 Service Unavailable.
+func (h *health) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.shuttingDown.Load() {
+		h.render(w, healthResponseData{Status: "shutting down"}, http.StatusServiceUnavailable)
+		return
+	}
+
+	checks := map[string]func(context.Context) error{
+		"database": h.DB.PingContext,
+		"schema":   h.checkSchema,
+		"disk":     h.checkDisk,
+	}
+
+	data := healthResponseData{Status: "ok", Checks: map[string]string{}}
+	status := http.StatusOK
+
+	for name, check := range checks {
+		if err := check(r.Context()); err != nil {
+			webx.LoggerFrom(r.Context()).Warn("readiness check failed",
+				slog.String("check", name),
+				slog.String("source", err.Error()))
+			data.Checks[name] = "failed"
+			data.Status = "not ready"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+
+		data.Checks[name] = "ok"
+	}
+
+	h.render(w, data, status)
+}
+
+// checkSchema liefert einen Fehler, wenn eine der Tabellen [requiredTables] fehlt.
+func (h *health) checkSchema(ctx context.Context) error {
+	rows, err := h.DB.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		tables = append(tables, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for _, table := range requiredTables {
+		if !slices.Contains(tables, table) {
+			missing = append(missing, table)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %v", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// checkDisk liefert einen Fehler, wenn SQLite im Verzeichnis Dir keine Dateien für das Write-Ahead-Log anlegen kann.
+func (h *health) checkDisk(ctx context.Context) error {
+	if h.Dir == "" {
+		return nil
+	}
+
+	file, err := os.CreateTemp(h.Dir, ".dinge-ready-*")
+	if err != nil {
+		return err
+	}
+
+	_, writeErr := file.Write([]byte("ok"))
+	closeErr := file.Close()
+	removeErr := os.Remove(file.Name())
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if closeErr != nil {
+		return closeErr
+	}
+
+	return removeErr
+}
+
+func (h *health) render(w http.ResponseWriter, data healthResponseData, status int) {
+	// Proxies dürfen das Ergebnis nicht zwischenspeichern.
+	w.Header().Set("Cache-Control", "no-store")
+
+	response := webx.JsonResponse[healthResponseData]{Data: data, StatusCode: status}
+	if err := response.Render(w); err != nil {
+		webx.ServerError(w, err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/photo"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/user"
+)
+
+func TestHealth(t *testing.T) {
+	schema := []string{ding.CreateScript, photo.CreateScript, user.CreateScript}
+
+	testcases := []struct {
+		name         string
+		scripts      []string
+		dir          func(t *testing.T) string
+		shuttingDown bool
+		status       int
+		want         healthResponseData
+	}{
+		{
+			name:    "ready",
+			scripts: schema,
+			dir:     func(t *testing.T) string { return t.TempDir() },
+			status:  http.StatusOK,
+			want:    healthResponseData{Status: "ok", Checks: map[string]string{"database": "ok", "schema": "ok", "disk": "ok"}},
+		},
+		{
+			name:    "in memory",
+			scripts: schema,
+			dir:     func(t *testing.T) string { return "" },
+			status:  http.StatusOK,
+			want:    healthResponseData{Status: "ok", Checks: map[string]string{"database": "ok", "schema": "ok", "disk": "ok"}},
+		},
+		{
+			name:   "missing schema",
+			dir:    func(t *testing.T) string { return t.TempDir() },
+			status: http.StatusServiceUnavailable,
+			want:   healthResponseData{Status: "not ready", Checks: map[string]string{"database": "ok", "schema": "failed", "disk": "ok"}},
+		},
+		{
+			name:    "directory not writable",
+			scripts: schema,
+			dir:     func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing") },
+			status:  http.StatusServiceUnavailable,
+			want:    healthResponseData{Status: "not ready", Checks: map[string]string{"database": "ok", "schema": "ok", "disk": "failed"}},
+		},
+		{
+			name:         "shutting down",
+			scripts:      schema,
+			dir:          func(t *testing.T) string { return t.TempDir() },
+			shuttingDown: true,
+			status:       http.StatusServiceUnavailable,
+			want:         healthResponseData{Status: "shutting down"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := sqlx.NewTestDatabase(tc.scripts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			health := &health{DB: db, Dir: tc.dir(t)}
+			if tc.shuttingDown {
+				health.ShutDown()
+			}
+
+			// Der Prozess läuft, auch wenn er nicht bereit ist.
+			live := httptest.NewRecorder()
+			health.Live(live, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if live.Code != http.StatusOK {
+				t.Errorf("GET /healthz status = %v; want %v", live.Code, http.StatusOK)
+			}
+
+			recorder := httptest.NewRecorder()
+			health.Ready(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if recorder.Code != tc.status {
+				t.Errorf("GET /readyz status = %v; want %v", recorder.Code, tc.status)
+			}
+
+			if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != "no-store" {
+				t.Errorf("GET /readyz Cache-Control = %q; want no-store", cacheControl)
+			}
+
+			var got healthResponseData
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Status != tc.want.Status || len(got.Checks) != len(tc.want.Checks) {
+				t.Fatalf("GET /readyz = %+v; want %+v", got, tc.want)
+			}
+
+			for name, result := range tc.want.Checks {
+				if got.Checks[name] != result {
+					t.Errorf("GET /readyz check %v = %q; want %q", name, got.Checks[name], result)
+				}
+			}
+		})
+	}
+
+	t.Run("database closed", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		recorder := httptest.NewRecorder()
+		(&health{DB: db}).Ready(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		if recorder.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET /readyz status = %v; want %v", recorder.Code, http.StatusServiceUnavailable)
+		}
+	})
+}
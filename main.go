@@ -4,6 +4,9 @@
 	Usage:
 
 	  dinge [flags]
+	  dinge photos migrate-storage [flags]
+	  dinge user add [--db-filename] [--role] <name>
+	  dinge user hash
 
 Die flags sind:
 
@@ -16,14 +19,52 @@ Die flags sind:
 		--db-filename
 		    Pfad zur Datenbankdatei.
 
+		--photo-storage
+		    Legt fest, wo der Inhalt der Photos gespeichert wird: "sqlite" (Voreinstellung), "filesystem" oder "s3".
+
+		--photo-dir
+		    Verzeichnis für die Photos, wenn --photo-storage=filesystem gewählt wurde.
+
+		--s3-endpoint, --s3-bucket, --s3-region, --s3-access-key, --s3-secret-key
+		    Zugang zum S3 kompatiblen Object Store, wenn --photo-storage=s3 gewählt wurde.
+
+		--photo-gc-interval
+		    Zeitabstand, in dem nicht mehr benötigte Photos entfernt und fehlende Wahrnehmungshashes älterer Photos ergänzt werden. Die Voreinstellung ist 1h.
+
+		--trash-retention
+		    Dauer, nach der Dinge im Papierkorb endgültig gelöscht werden. Die Voreinstellung ist 720h (30 Tage). Mit 0 werden Dinge nicht automatisch gelöscht.
+
+		--shutdown-delay
+		    Dauer zwischen der Meldung "nicht bereit" auf /readyz und dem Beenden des Servers. Die Voreinstellung ist 5s. Mit 0 beendet sich der Server sofort.
+
+		--tls-cert, --tls-key
+		    Zertifikat und privater Schlüssel für TLS im PEM Format. Fehlen beide, verwendet der Server ein selbst signiertes Zertifikat für den Namen und die Adressen des Rechners im lokalen Netz. Es wird als dinge-cert.pem und dinge-key.pem im Verzeichnis der Datenbankdatei gespeichert und neu erzeugt, wenn es abgelaufen ist oder sich die Adressen ändern.
+
+		--http-redirect-address
+		    Adresse, an der ein zweiter Server Anfragen über HTTP zu HTTPS umleitet, zum Beispiel "0.0.0.0:8080". Ohne diesen Parameter gibt es keine Umleitung.
+
+		--no-tls
+		    Der Server verwendet HTTP statt HTTPS, zum Beispiel hinter einem Reverse Proxy oder einem SSH Tunnel.
+
+		--basic-auth-file
+		    Datei mit Zugangsdaten für HTTP Basic Authentication. Jede Zeile enthält Benutzername, Rolle und bcrypt Hash des Passworts, getrennt durch Doppelpunkte.
+
+		--insecure-cookies
+		    Sendet das Sitzungscookie und das CSRF Cookie auch über unverschlüsselte Verbindungen. Nur für die Entwicklung ohne TLS.
+
 		--version -v
 		    Gibt die Version aus.
 				Der Server wird nicht gestartet.
+
+Der Befehl user add legt einen Benutzer an, der sich am Server anmelden kann. Die Rolle (viewer, clerk, editor oder admin) legt fest, was der Benutzer darf. Die Voreinstellung ist viewer. Das Passwort wird von der Standardeingabe gelesen.
+
+Der Befehl user hash gibt den bcrypt Hash des Passworts von der Standardeingabe aus, zum Beispiel für die Datei --basic-auth-file.
 */
 package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"errors"
 	"flag"
@@ -33,16 +74,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/haschi/dinge/about"
+	"github.com/haschi/dinge/barcode"
 	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/metrics"
 	"github.com/haschi/dinge/photo"
 	"github.com/haschi/dinge/sqlx"
 	"github.com/haschi/dinge/system"
 	"github.com/haschi/dinge/templates"
+	"github.com/haschi/dinge/user"
+	"github.com/haschi/dinge/webx"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -58,21 +104,53 @@ func main() {
 	}
 }
 
-func run(ctx context.Context, stdout io.Writer, _ []string, environment func(string) (string, bool)) error {
+func run(ctx context.Context, stdout io.Writer, args []string, environment func(string) (string, bool)) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
 
+	if len(args) > 1 {
+		switch args[1] {
+		case "photos":
+			return runPhotos(ctx, stdout, args[2:], environment)
+		case "user":
+			return runUser(ctx, os.Stdin, stdout, args[2:], environment)
+		}
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+
 	httpAddress := environmentOrDefault(environment, "HTTP_ADDRESS", "0.0.0.0:8443")
-	flag.StringVar(&httpAddress, "address", httpAddress, "HTTP network address")
+	flags.StringVar(&httpAddress, "address", httpAddress, "HTTP network address")
 
 	datasource := environmentOrDefault(environment, "DB_FILENAME", "dinge.db")
-	flag.StringVar(&datasource, "db-filename", datasource, "Database filename")
+	flags.StringVar(&datasource, "db-filename", datasource, "Database filename")
+
+	storageConfig := newStorageConfig(flags, environment)
+	tlsConfig := newTLSConfig(flags, environment)
+
+	gcInterval := time.Hour
+	flags.DurationVar(&gcInterval, "photo-gc-interval", gcInterval, "Interval for removing unused photos")
+
+	trashRetention := 30 * 24 * time.Hour
+	flags.DurationVar(&trashRetention, "trash-retention", trashRetention, "Duration after which dinge in the trash are deleted permanently; 0 keeps them")
+
+	basicAuthFile := environmentOrDefault(environment, "BASIC_AUTH_FILE", "")
+	flags.StringVar(&basicAuthFile, "basic-auth-file", basicAuthFile, "File with HTTP Basic credentials name:role:bcrypt-hash")
+
+	shutdownDelay, err := time.ParseDuration(environmentOrDefault(environment, "SHUTDOWN_DELAY", "5s"))
+	if err != nil {
+		return fmt.Errorf("SHUTDOWN_DELAY: %w", err)
+	}
+	flags.DurationVar(&shutdownDelay, "shutdown-delay", shutdownDelay, "Duration between reporting not ready on /readyz and shutting down, 0 to shut down immediately")
+
+	var insecureCookies bool
+	flags.BoolVar(&insecureCookies, "insecure-cookies", insecureCookies, "Send the session cookie over unencrypted connections too")
 
 	var version bool
-	flag.BoolVar(&version, "version", false, "print version information")
-	flag.BoolVar(&version, "v", false, "print version information (shorthand)")
+	flags.BoolVar(&version, "version", false, "print version information")
+	flags.BoolVar(&version, "v", false, "print version information (shorthand)")
 
-	flag.Parse()
+	flags.Parse(args[1:])
 
 	if version {
 		// TODO: Revision und Version richtig reinkompilieren.
@@ -122,14 +200,27 @@ func run(ctx context.Context, stdout io.Writer, _ []string, environment func(str
 	}
 
 	clock := system.RealClock{}
+	storage, err := storageConfig.Storage(storageConfig.Kind, tm)
+	if err != nil {
+		return err
+	}
+
 	photoRepository := &photo.Repository{
-		Clock: clock,
-		Tm:    tm,
+		Clock:   clock,
+		Tm:      tm,
+		Storage: storage,
+	}
+
+	staticFiles, staticHandler, err := newStaticHandler(logger)
+	if err != nil {
+		return err
 	}
 
+	templateFiles := webx.Templates{FS: templates.TemplatesFileSystem, Assets: staticFiles}
+
 	photos := &photo.Module{
 		Repository: photoRepository,
-		Templates:  templates.TemplatesFileSystem,
+		Templates:  templateFiles,
 	}
 
 	dingRepository := &ding.Repository{
@@ -138,15 +229,86 @@ func run(ctx context.Context, stdout io.Writer, _ []string, environment func(str
 	}
 
 	dinge := &ding.Module{
-		Repository: dingRepository,
-		Templates:  templates.TemplatesFileSystem,
-		Photos:     photos,
+		Repository:  dingRepository,
+		Templates:   templateFiles,
+		Photos:      photos,
+		PhotoUrls:   photoRepository,
+		Lookalikes:  photoRepository,
+		PhotoMerger: photoRepository,
+
+		TrashRetention: trashRetention,
+	}
+
+	aboutResource := about.Module{Templates: templateFiles}
+	barcodes := &barcode.Module{}
+	userRepository := &user.Repository{Clock: clock, Tm: tm}
+	users := &user.Module{
+		Repository:      userRepository,
+		Templates:       templateFiles,
+		InsecureCookies: insecureCookies,
+	}
+
+	tokens := &user.TokenModule{
+		Repository: userRepository,
+		Templates:  templateFiles,
+	}
+
+	authenticators := webx.Authenticators{users, user.TokenAuthenticator{Repository: userRepository}}
+	if basicAuthFile != "" {
+		basic, err := readBasicCredentials(basicAuthFile)
+		if err != nil {
+			return err
+		}
+		authenticators = append(authenticators, basic)
+	}
+
+	// Der Schlüssel wird bei jedem Start neu erzeugt. Danach geöffnete Formulare erhalten ein neues Token.
+	csrfKey, err := webx.NewCSRFKey()
+	if err != nil {
+		return err
 	}
 
-	staticHandler := newStaticHandler(logger)
-	aboutResource := about.Module{Templates: templates.TemplatesFileSystem}
-	routes := routes(logger, staticHandler, &aboutResource, dinge, photos)
-	routes.HandleFunc("GET /photos/{id}", photos.Download)
+	csrf := webx.CSRF{Key: csrfKey, InsecureCookies: insecureCookies}
+
+	headers := webx.DefaultSecurityHeaders
+	if tlsConfig.Disabled {
+		// Hinter einem Reverse Proxy entscheidet der Proxy über HSTS.
+		headers.HSTSMaxAge = 0
+	}
+
+	errorPages := webx.ErrorPages{Templates: templateFiles, Logger: logger}
+
+	httpMetrics := &metrics.HTTP{}
+	metricsHandler := metrics.Handler{Collectors: []metrics.Collector{
+		httpMetrics,
+		metrics.Database{DB: db, Transactions: tm},
+		inventoryMetrics(dingRepository),
+	}}
+
+	health := &health{DB: db}
+	if !strings.Contains(datasource, "mode=memory") {
+		health.Dir = filepath.Dir(datasource)
+	}
+
+	routes := routes(routesConfig{
+		Logger:        logger,
+		Headers:       headers,
+		ErrorPages:    errorPages,
+		CSRF:          csrf,
+		Authenticator: authenticators,
+		HTTPMetrics:   httpMetrics,
+		Metrics:       metricsHandler,
+		Users:         users,
+		Tokens:        tokens,
+		About:         &aboutResource,
+		Dinge:         dinge,
+		Photos:        photos,
+		Barcodes:      barcodes,
+		Static:        staticHandler,
+		PhotoDownload: http.HandlerFunc(photos.Download),
+		Liveness:      http.HandlerFunc(health.Live),
+		Readiness:     http.HandlerFunc(health.Ready),
+	})
 
 	server := &http.Server{
 		Addr:     httpAddress,
@@ -154,28 +316,77 @@ func run(ctx context.Context, stdout io.Writer, _ []string, environment func(str
 		Handler:  routes,
 	}
 
+	// Kamera und Barcode API benötigen im Browser einen Secure Context. Daher bietet der Server TLS an, wenn es nicht abgeschaltet ist.
+	servers := []*http.Server{server}
+	if !tlsConfig.Disabled {
+		certificate, err := tlsConfig.Certificate(logger, filepath.Dir(datasource))
+		if err != nil {
+			return err
+		}
+
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+
+		if tlsConfig.RedirectAddress != "" {
+			redirect, err := redirectToHTTPS(httpAddress)
+			if err != nil {
+				return err
+			}
+
+			servers = append(servers, &http.Server{
+				Addr:     tlsConfig.RedirectAddress,
+				ErrorLog: server.ErrorLog,
+				Handler:  webx.Combine(redirect, webx.LogRequest(logger)),
+			})
+		}
+	}
+
+	go collectPhotoGarbage(ctx, logger, photoRepository, gcInterval)
+
+	if trashRetention > 0 {
+		go emptyTrash(ctx, logger, dingRepository, trashRetention)
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	go func() {
-		logger.Info("starting http server", slog.String("address", httpAddress))
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Error("error listening and serving", slog.String("source", err.Error()))
-		}
-		logger.Info("Stopped serving new connections")
-	}()
+	for _, server := range servers {
+		go func() {
+			logger.Info("starting http server", slog.String("address", server.Addr), slog.Bool("tls", server.TLSConfig != nil))
+
+			var err error
+			if server.TLSConfig != nil {
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("error listening and serving", slog.String("source", err.Error()))
+			}
+			logger.Info("Stopped serving new connections", slog.String("address", server.Addr))
+		}()
+	}
 
 	go func() {
 		defer wg.Done()
 		<-ctx.Done()
 
+		// Der Reverse Proxy erkennt an /readyz, dass er keine neuen Anfragen mehr senden soll.
+		health.ShutDown()
+		if shutdownDelay > 0 {
+			logger.Info("not ready, waiting before shutdown", slog.Duration("delay", shutdownDelay))
+			time.Sleep(shutdownDelay)
+		}
+
 		shutdownCtx := context.Background()
 		shutdownCtx, cancel := context.WithTimeout(shutdownCtx, 20*time.Second)
 		defer cancel()
 
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			logger.Error("error shutting down http server",
-				slog.String("source", err.Error()))
+		for _, server := range servers {
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				logger.Error("error shutting down http server",
+					slog.String("source", err.Error()))
+			}
 		}
 
 		logger.Info("graceful shutdown complete")
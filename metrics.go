@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/metrics"
+)
+
+// inventoryMetrics liefert die Kennzahlen des Bestands als Metriken, siehe [ding.Repository.Statistics].
+func inventoryMetrics(repository *ding.Repository) metrics.CollectorFunc {
+	return func(ctx context.Context, w *metrics.Writer) error {
+		statistics, err := repository.Statistics(ctx)
+		if err != nil {
+			return err
+		}
+
+		w.Family("dinge_items", metrics.KindGauge, "Number of dinge by state.")
+		w.Sample("dinge_items", float64(statistics.Dinge), metrics.Label{Name: "state", Value: "active"})
+		w.Sample("dinge_items", float64(statistics.Archived), metrics.Label{Name: "state", Value: "archived"})
+
+		w.Family("dinge_stock", metrics.KindGauge, "Total count of all dinge outside the trash.")
+		w.Sample("dinge_stock", float64(statistics.Stock))
+
+		w.Family("dinge_events_total", metrics.KindCounter, "Number of events in the history by operation.")
+		for _, operation := range slices.Sorted(maps.Keys(statistics.Events)) {
+			w.Sample("dinge_events_total", float64(statistics.Events[operation]), metrics.Label{Name: "operation", Value: operation})
+		}
+
+		return nil
+	}
+}
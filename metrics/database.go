@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/haschi/dinge/sqlx"
+)
+
+// Database liefert Metriken des Connection Pools der Datenbank und die Anzahl offener Transaktionen.
+type Database struct {
+	DB           *sql.DB
+	Transactions sqlx.TransactionManager
+}
+
+func (d Database) Collect(ctx context.Context, w *Writer) error {
+	stats := d.DB.Stats()
+
+	gauges := []struct {
+		name  string
+		help  string
+		value int
+	}{
+		{"dinge_db_max_open_connections", "Maximum number of open connections to the database; 0 is unlimited.", stats.MaxOpenConnections},
+		{"dinge_db_open_connections", "Number of established connections to the database, in use and idle.", stats.OpenConnections},
+		{"dinge_db_in_use_connections", "Number of connections currently in use.", stats.InUse},
+		{"dinge_db_idle_connections", "Number of idle connections.", stats.Idle},
+		{"dinge_db_open_transactions", "Number of open transactions of the transaction manager.", d.Transactions.Count()},
+	}
+
+	for _, gauge := range gauges {
+		w.Family(gauge.name, KindGauge, gauge.help)
+		w.Sample(gauge.name, float64(gauge.value))
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"dinge_db_wait_count_total", "Total number of connections waited for.", float64(stats.WaitCount)},
+		{"dinge_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", stats.WaitDuration.Seconds()},
+		{"dinge_db_max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.", float64(stats.MaxIdleClosed)},
+		{"dinge_db_max_idle_time_closed_total", "Total number of connections closed due to SetConnMaxIdleTime.", float64(stats.MaxIdleTimeClosed)},
+		{"dinge_db_max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.", float64(stats.MaxLifetimeClosed)},
+	}
+
+	for _, counter := range counters {
+		w.Family(counter.name, KindCounter, counter.help)
+		w.Sample(counter.name, counter.value)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets sind die Obergrenzen der Klassen für die Dauer von Anfragen in Sekunden.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	method string
+	route  string
+	code   int
+}
+
+type durationKey struct {
+	method string
+	route  string
+}
+
+type histogram struct {
+	// counts enthält für jede Klasse die Anzahl der Beobachtungen bis zu ihrer Obergrenze, ohne die Klasse +Inf.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// HTTP ist ein Middleware Handler, der Anfragen zählt und ihre Dauer misst.
+//
+// Die Metriken unterscheiden Anfragen nach dem Muster des [http.ServeMux], zum Beispiel /dinge/{id}, und nicht nach ihrer Adresse. So bleibt die Anzahl der Zeitreihen begrenzt. Die Middleware muss daher für jede Route des ServeMux angewendet werden. Anfragen, die keiner Route entsprechen, erfasst sie nicht.
+type HTTP struct {
+	// Buckets sind die Obergrenzen der Klassen für die Dauer. Ohne Buckets gelten [DefaultBuckets].
+	Buckets []float64
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[durationKey]*histogram
+}
+
+func (m *HTTP) Apply(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(writer, r)
+
+		m.observe(r.Method, route(r.Pattern), writer.statusCode, time.Since(start))
+	})
+}
+
+func (m *HTTP) buckets() []float64 {
+	if len(m.Buckets) == 0 {
+		return DefaultBuckets
+	}
+	return m.Buckets
+}
+
+func (m *HTTP) observe(method string, route string, code int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.requests == nil {
+		m.requests = map[requestKey]uint64{}
+		m.durations = map[durationKey]*histogram{}
+	}
+
+	m.requests[requestKey{method: method, route: route, code: code}]++
+
+	key := durationKey{method: method, route: route}
+	h, ok := m.durations[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets()))}
+		m.durations[key] = h
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range m.buckets() {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += seconds
+	h.count++
+}
+
+// Collect schreibt die Metriken http_requests_total und http_request_duration_seconds.
+func (m *HTTP) Collect(ctx context.Context, w *Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Family("http_requests_total", KindCounter, "Number of HTTP requests by method, route pattern and status code.")
+	requests := slices.SortedFunc(maps.Keys(m.requests), func(a, b requestKey) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method), cmp.Compare(a.code, b.code))
+	})
+
+	for _, key := range requests {
+		w.Sample("http_requests_total", float64(m.requests[key]),
+			Label{Name: "method", Value: key.method},
+			Label{Name: "route", Value: key.route},
+			Label{Name: "code", Value: strconv.Itoa(key.code)})
+	}
+
+	w.Family("http_request_duration_seconds", KindHistogram, "Duration of HTTP requests by method and route pattern.")
+	durations := slices.SortedFunc(maps.Keys(m.durations), func(a, b durationKey) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method))
+	})
+
+	for _, key := range durations {
+		h := m.durations[key]
+		method := Label{Name: "method", Value: key.method}
+		route := Label{Name: "route", Value: key.route}
+
+		for i, bound := range m.buckets() {
+			w.Sample("http_request_duration_seconds_bucket", float64(h.counts[i]), method, route, Label{Name: "le", Value: formatValue(bound)})
+		}
+
+		w.Sample("http_request_duration_seconds_bucket", float64(h.count), method, route, Label{Name: "le", Value: "+Inf"})
+		w.Sample("http_request_duration_seconds_sum", h.sum, method, route)
+		w.Sample("http_request_duration_seconds_count", float64(h.count), method, route)
+	}
+
+	return nil
+}
+
+// route liefert das Muster ohne Methode, zum Beispiel /dinge/{id} für GET /dinge/{id}.
+func route(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
+
+// statusResponseWriter merkt sich den HTTP Status Code der Antwort.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.statusCode = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
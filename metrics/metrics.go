@@ -0,0 +1,133 @@
+// Package metrics stellt Metriken der Anwendung im Textformat von Prometheus bereit.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/haschi/dinge/webx"
+)
+
+// ContentType ist der Content-Type des Textformats von Prometheus.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metrikarten des Textformats.
+const (
+	KindCounter   = "counter"
+	KindGauge     = "gauge"
+	KindHistogram = "histogram"
+)
+
+// Label ist ein Name-Wert-Paar, das eine Zeitreihe einer Metrik unterscheidet.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Collector liefert Metriken, wenn Prometheus sie abruft.
+type Collector interface {
+	Collect(ctx context.Context, w *Writer) error
+}
+
+// CollectorFunc ist eine Funktion, die [Collector] implementiert.
+type CollectorFunc func(ctx context.Context, w *Writer) error
+
+func (fn CollectorFunc) Collect(ctx context.Context, w *Writer) error {
+	return fn(ctx, w)
+}
+
+// Writer schreibt Metriken im Textformat von Prometheus.
+//
+// Der erste Fehler beim Schreiben beendet die Ausgabe. Err liefert diesen Fehler.
+type Writer struct {
+	out io.Writer
+	err error
+}
+
+// NewWriter erzeugt einen Writer, der nach out schreibt.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// Err liefert den ersten Fehler beim Schreiben.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Family beginnt die Metrik name der Art kind mit der Beschreibung help.
+func (w *Writer) Family(name string, kind string, help string) {
+	w.printf("# HELP %v %v\n# TYPE %v %v\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help), name, kind)
+}
+
+// Sample schreibt den Wert value der Zeitreihe mit den Labels labels.
+func (w *Writer) Sample(name string, value float64, labels ...Label) {
+	w.printf("%v%v %v\n", name, formatLabels(labels), formatValue(value))
+}
+
+func (w *Writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.out, format, args...)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = fmt.Sprintf(`%v="%v"`, label.Name, labelEscaper.Replace(label.Value))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// Handler gibt die Metriken aller Collectors aus.
+type Handler struct {
+	Collectors []Collector
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+
+	for _, collector := range h.Collectors {
+		if err := collector.Collect(r.Context(), writer); err != nil {
+			webx.ServerError(w, err)
+			return
+		}
+	}
+
+	if err := writer.Err(); err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := buffer.WriteTo(w); err != nil {
+		webx.LoggerFrom(r.Context()).Error("writing metrics", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,81 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haschi/dinge/metrics"
+	"github.com/haschi/dinge/sqlx"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestWriter(t *testing.T) {
+	var out strings.Builder
+	w := metrics.NewWriter(&out)
+
+	w.Family("test_value", metrics.KindGauge, "Help with \\ and\nnewline.")
+	w.Sample("test_value", 1.5, metrics.Label{Name: "name", Value: "quote \" backslash \\ newline \n"})
+	w.Sample("test_value", 3)
+
+	want := "# HELP test_value Help with \\\\ and\\nnewline.\n" +
+		"# TYPE test_value gauge\n" +
+		"test_value{name=\"quote \\\" backslash \\\\ newline \\n\"} 1.5\n" +
+		"test_value 3\n"
+
+	if out.String() != want {
+		t.Errorf("Writer output = %q; want %q", out.String(), want)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	db, err := sqlx.NewTestDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := tm.BeginTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	recorder := httptest.NewRecorder()
+	handler := metrics.Handler{Collectors: []metrics.Collector{metrics.Database{DB: db, Transactions: tm}}}
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE dinge_db_open_connections gauge\n",
+		"dinge_db_in_use_connections 1\n",
+		"dinge_db_open_transactions 1\n",
+		"# TYPE dinge_db_wait_count_total counter\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics do not contain %q:\n%v", line, body)
+		}
+	}
+}
+
+func TestHandler_Error(t *testing.T) {
+	failing := metrics.CollectorFunc(func(ctx context.Context, w *metrics.Writer) error {
+		return errors.New("collector failed")
+	})
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler{Collectors: []metrics.Collector{failing}}.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status = %v; want %v", recorder.Code, http.StatusInternalServerError)
+	}
+}
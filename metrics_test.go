@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/metrics"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+)
+
+func TestInventoryMetrics(t *testing.T) {
+	db, err := sqlx.NewTestDatabase(ding.CreateScript, ding.FixtureScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	writer := metrics.NewWriter(&out)
+	collector := inventoryMetrics(&ding.Repository{Clock: system.RealClock{}, Tm: tm})
+	if err := collector.Collect(context.Background(), writer); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"# TYPE dinge_items gauge\n",
+		`dinge_items{state="active"} 3` + "\n",
+		"# TYPE dinge_stock gauge\n",
+		"dinge_stock 6\n",
+		"# TYPE dinge_events_total counter\n",
+		`dinge_events_total{operation="new"} 3` + "\n",
+	}
+
+	for _, line := range want {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("metrics do not contain %q:\n%v", line, out.String())
+		}
+	}
+}
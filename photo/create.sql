@@ -1,7 +1,30 @@
 CREATE TABLE photos(
-  photo BLOB NOT NULL,
+  photo BLOB,
   mime_type VARCHAR(100) NOT NULL,
-  dinge_id INTEGER NOT NULL REFERENCES dinge
+  hash VARCHAR(64) NOT NULL,
+  phash INTEGER,
+  -- Zeitpunkt, zu dem der Wahrnehmungshash nicht nachgetragen werden konnte. Siehe Repository.BackfillPerceptualHashes
+  phash_versucht DATETIME,
+  aktualisiert DATETIME NOT NULL,
+  -- Wird ein Ding endgültig gelöscht, wird auch sein Photo entfernt.
+  dinge_id INTEGER NOT NULL REFERENCES dinge ON DELETE CASCADE
 );
 
 CREATE UNIQUE INDEX idx_photos_dinge_id ON photos(dinge_id);
+CREATE INDEX idx_photos_hash ON photos(hash);
+
+-- Hashes von Inhalten, die möglicherweise nicht mehr benötigt werden. Siehe Repository.CollectGarbage
+CREATE TABLE photo_garbage(
+  hash VARCHAR(64) NOT NULL PRIMARY KEY
+);
+
+CREATE TRIGGER trg_photos_delete AFTER DELETE ON photos
+BEGIN
+  INSERT OR IGNORE INTO photo_garbage(hash) VALUES(old.hash);
+END;
+
+CREATE TRIGGER trg_photos_update AFTER UPDATE OF hash ON photos
+WHEN old.hash <> new.hash
+BEGIN
+  INSERT OR IGNORE INTO photo_garbage(hash) VALUES(old.hash);
+END;
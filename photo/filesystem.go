@@ -0,0 +1,102 @@
+package photo
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FilesystemStorage speichert den Inhalt der Photos inhaltsadressiert im lokalen Dateisystem.
+//
+// Jeder Inhalt wird in der Datei Dir/xy/hash gespeichert, wobei xy die ersten beiden Zeichen des Hashes sind. Dadurch bleibt die Anzahl der Dateien in einem Verzeichnis überschaubar.
+type FilesystemStorage struct {
+	Dir string
+}
+
+func (s FilesystemStorage) path(key string) string {
+	return filepath.Join(s.Dir, key[:2], key)
+}
+
+func (s FilesystemStorage) Put(ctx context.Context, key string, data []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path := s.path(key)
+	if _, err := os.Stat(path); err == nil {
+		// Gleicher Hash, gleicher Inhalt.
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	// Erst in eine temporäre Datei schreiben und dann umbenennen, damit
+	// niemals eine unvollständige Datei unter dem Schlüssel liegt.
+	file, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (s FilesystemStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s FilesystemStorage) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -1,5 +1,5 @@
 
-INSERT INTO photos(photo, mime_type, dinge_id)
-VALUES (X'0123456789', 'image/png', 1),
-  (X'1234567890', 'image/jpeg', 2),
-  (X'2345678901', 'image/webp', 3);
+INSERT INTO photos(photo, mime_type, hash, phash, aktualisiert, dinge_id)
+VALUES (X'0123456789', 'image/png', 'db24abe2d0a20fac957859eb755b3dbf695becdb9f5890e3a2a2411273ae50ad', 1085102592571150095, '2024-11-13 18:48:01', 1),
+  (X'1234567890', 'image/jpeg', '6c450e037e79b76f231a71a22ff40403f7d9b74b15e014e52fe1156d3666c3e6', 1085102592571150092, '2024-11-13 19:05:02', 2),
+  (X'2345678901', 'image/webp', '219e83aaf84a469cde2c76bdc92cbee355b4d08443ffcf3b67f12bed29c2ab86', -1085102592571150096, '2024-11-13 19:06:03', 3);
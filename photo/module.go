@@ -4,17 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"image"
-	"io"
 	"io/fs"
 	"net/http"
 	"strconv"
 
 	"github.com/haschi/dinge/validation"
 	"github.com/haschi/dinge/webx"
-
-	_ "image/jpeg"
-	_ "image/png"
 )
 
 type Module struct {
@@ -23,8 +18,12 @@ type Module struct {
 }
 
 func (m *Module) Mount(mux *http.ServeMux, prefix string, middleware ...webx.Middleware) {
-	mux.Handle(fmt.Sprintf("GET %v/", prefix), webx.CombineFunc(m.Form, middleware...))
-	mux.Handle(fmt.Sprintf("POST %v/", prefix), webx.CombineFunc(m.Upload, middleware...))
+	editor := webx.Authorize(webx.RoleEditor, middleware...)
+
+	mux.Handle(fmt.Sprintf("GET %v/", prefix), webx.CombineFunc(m.Form, editor...))
+	mux.Handle(fmt.Sprintf("POST %v/", prefix), webx.CombineFunc(m.Upload, editor...))
+	mux.Handle(fmt.Sprintf("GET %v/photo/delete", prefix), webx.CombineFunc(m.DeleteForm, editor...))
+	mux.Handle(fmt.Sprintf("POST %v/photo/delete", prefix), webx.CombineFunc(m.Delete, editor...))
 }
 
 // Form liefert eine Ansicht für die Bearbeitung eines Photos
@@ -32,7 +31,7 @@ func (res Module) Form(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil || id < 1 {
-		http.NotFound(w, r)
+		webx.NotFound(w, r, res.Templates)
 		return
 	}
 
@@ -45,7 +44,7 @@ func (res Module) Form(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		if errors.Is(err, ErrNoRecord) {
-			http.NotFound(w, r)
+			webx.NotFound(w, r, res.Templates)
 			return
 		}
 		webx.ServerError(w, err)
@@ -76,17 +75,17 @@ const Megabyte = 20
 func (res Module) Upload(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil || id < 1 {
-		http.NotFound(w, r)
+		webx.NotFound(w, r, res.Templates)
 		return
 	}
 
-	image, err := imageFromForm(r, "file", 1<<Megabyte)
+	image, err := webx.ImageFromForm(r, "file", 1<<Megabyte)
 	if err != nil {
 
 		defaultValues := webx.TemplateData[PhotoData]{
 			Scripts:          []string{"/static/photo.js"},
 			Styles:           []string{"/static/css/photo.css"},
-			FormValues:       PhotoData{Id: id, PhotoUrl: "/static/placeholder.svg"},
+			FormValues:       PhotoData{Id: id, PhotoUrl: PlaceholderUrl},
 			ValidationErrors: validation.ErrorMap{"file": "Fehlerhaft Bilddatei"},
 		}
 
@@ -112,64 +111,89 @@ func (res Module) Upload(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// Download liefert ein in der Datenbank gespeichertes Photo aus
-func (res Module) Download(w http.ResponseWriter, r *http.Request) {
+// DeleteForm fragt nach, ob das Photo eines Dings wirklich entfernt werden soll.
+func (res Module) DeleteForm(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil || id < 1 {
-		http.NotFound(w, r)
+		webx.NotFound(w, r, res.Templates)
 		return
 	}
 
-	// http.DetectContentType()
-
-	photo, err := res.Repository.GetPhotoById(r.Context(), id)
+	url, err := res.Repository.GetUrl(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, ErrNoRecord) {
-			http.NotFound(w, r)
-			return
-		}
-
 		webx.ServerError(w, err)
 		return
 	}
 
-	reader := bytes.NewReader(photo)
-	w.Header().Set("Content-Type", "image/png") // TODO: Den richtigen Mime Type ermitteln
-	w.WriteHeader(http.StatusOK)
-	io.Copy(w, reader)
-}
-
-// TODO: Nach webx verschieben.
-func imageFromForm(r *http.Request, field string, limit int64) (image.Image, error) {
+	data := PhotoData{Id: id, PhotoUrl: url}
+	if !data.HasPhoto() {
+		webx.NotFound(w, r, res.Templates)
+		return
+	}
 
-	reader, err := r.MultipartReader()
-	if err != nil {
-		return nil, err
+	response := webx.HtmlResponse[PhotoData]{
+		TemplateName: "photodelete",
+		Data:         webx.TemplateData[PhotoData]{FormValues: data},
+		StatusCode:   http.StatusOK,
 	}
 
-	part, err := reader.NextPart()
-	if err != nil {
+	if err := response.Render(w, res.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
 
-		return nil, err
+// Delete entfernt das Photo eines Dings.
+//
+// Ziel der Form von DeleteForm. Anschließend zeigt die Detailansicht des Dings wieder das Platzhalterbild.
+func (res Module) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		webx.NotFound(w, r, res.Templates)
+		return
 	}
 
-	defer part.Close()
+	if err := res.Repository.PhotoEntfernen(r.Context(), id); err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			webx.NotFound(w, r, res.Templates)
+			return
+		}
 
-	formname := part.FormName()
-	if formname != field {
-		return nil, fmt.Errorf("unexpected field in multipart form %v", formname)
+		webx.ServerError(w, err)
+		return
 	}
 
-	// contentType := part.Header.Get("Content-Type")
-	// if contentType != mime.
+	webx.SeeOther("/dinge/%v", id).ServeHTTP(w, r)
+}
 
-	lr := io.LimitReader(part, limit)
+// Download liefert ein in der Datenbank gespeichertes Photo aus
+//
+// Die Antwort enthält den gespeicherten MIME Type sowie einen ETag, so dass Browser bedingte Anfragen stellen können und nur geänderte Photos erneut laden. Enthält die URL die aktuelle Version des Photos, darf der Browser die Antwort dauerhaft zwischenspeichern. Range Anfragen werden von [http.ServeContent] bearbeitet.
+func (res Module) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		webx.NotFound(w, r, res.Templates)
+		return
+	}
 
-	im, _, err := image.Decode(lr)
+	photo, err := res.Repository.GetPhotoById(r.Context(), id)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, ErrNoRecord) {
+			webx.NotFound(w, r, res.Templates)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
 	}
 
-	return im, nil
+	w.Header().Set("Content-Type", photo.MimeType)
+	w.Header().Set("ETag", photo.ETag())
+
+	if r.URL.Query().Get("v") == photo.Hash {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
 
+	http.ServeContent(w, r, "", photo.Aktualisiert, bytes.NewReader(photo.Data))
 }
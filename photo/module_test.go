@@ -1,9 +1,14 @@
 package photo_test
 
 import (
+	"context"
 	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/haschi/dinge/ding"
 	"github.com/haschi/dinge/photo"
 	"github.com/haschi/dinge/sqlx"
 	"github.com/haschi/dinge/system"
@@ -58,6 +63,177 @@ func TestResource_Form(t *testing.T) {
 	}
 }
 
+func TestModule_Delete(t *testing.T) {
+
+	type testcase struct {
+		name           string
+		method         string
+		path           string
+		wantStatusCode int
+		wantLocation   string
+	}
+
+	testcases := []testcase{
+		{
+			name:           "confirm deletion",
+			method:         http.MethodGet,
+			path:           "/dinge/1/photo/delete",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "confirm deletion of unknown photo",
+			method:         http.MethodGet,
+			path:           "/dinge/42/photo/delete",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "delete photo",
+			method:         http.MethodPost,
+			path:           "/dinge/1/photo/delete",
+			wantStatusCode: http.StatusSeeOther,
+			wantLocation:   "/dinge/1",
+		},
+		{
+			name:           "delete unknown photo",
+			method:         http.MethodPost,
+			path:           "/dinge/42/photo/delete",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			scripts := sqlx.Execute(ding.CreateScript, photo.CreateScript, ding.FixtureScript, photo.FixtureScript)
+			config := webx.TestserverConfig{
+				Database:   webx.InMemoryDatabase(),
+				Module:     NewPhotoTestModule(scripts),
+				Middleware: []webx.Middleware{},
+			}
+
+			testserver := webx.NewTestserver(t, "/dinge/{id}", config)
+			defer testserver.Close()
+
+			var response *http.Response
+			if testcase.method == http.MethodPost {
+				response = testserver.Post(testcase.path, url.Values{})
+			} else {
+				response = testserver.Get(testcase.path)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != testcase.wantStatusCode {
+				t.Errorf("%v %v status = %v; want %v", testcase.method, testcase.path, response.StatusCode, testcase.wantStatusCode)
+			}
+
+			if location := response.Header.Get("Location"); location != testcase.wantLocation {
+				t.Errorf("%v %v Location = %v; want %v", testcase.method, testcase.path, location, testcase.wantLocation)
+			}
+		})
+	}
+}
+
+func TestModule_Download(t *testing.T) {
+
+	const etag = `"db24abe2d0a20fac957859eb755b3dbf695becdb9f5890e3a2a2411273ae50ad"`
+
+	type args struct {
+		id     string
+		query  string
+		header http.Header
+	}
+
+	type want struct {
+		statusCode   int
+		contentType  string
+		cacheControl string
+		body         string
+	}
+
+	testcases := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "photo with stored mime type",
+			args: args{id: "2"},
+			want: want{statusCode: http.StatusOK, contentType: "image/jpeg", cacheControl: "no-cache", body: "\x12\x34\x56\x78\x90"},
+		},
+		{
+			name: "versioned url",
+			args: args{id: "1", query: "?v=db24abe2d0a20fac957859eb755b3dbf695becdb9f5890e3a2a2411273ae50ad"},
+			want: want{statusCode: http.StatusOK, contentType: "image/png", cacheControl: "public, max-age=31536000, immutable", body: "\x01\x23\x45\x67\x89"},
+		},
+		{
+			name: "outdated version",
+			args: args{id: "1", query: "?v=outdated"},
+			want: want{statusCode: http.StatusOK, contentType: "image/png", cacheControl: "no-cache", body: "\x01\x23\x45\x67\x89"},
+		},
+		{
+			name: "if none match",
+			args: args{id: "1", header: http.Header{"If-None-Match": []string{etag}}},
+			want: want{statusCode: http.StatusNotModified, cacheControl: "no-cache"},
+		},
+		{
+			name: "if modified since",
+			args: args{id: "1", header: http.Header{"If-Modified-Since": []string{"Thu, 14 Nov 2024 00:00:00 GMT"}}},
+			want: want{statusCode: http.StatusNotModified, cacheControl: "no-cache"},
+		},
+		{
+			name: "range",
+			args: args{id: "1", header: http.Header{"Range": []string{"bytes=1-2"}}},
+			want: want{statusCode: http.StatusPartialContent, contentType: "image/png", cacheControl: "no-cache", body: "\x23\x45"},
+		},
+		{
+			name: "unknown photo",
+			args: args{id: "42"},
+			want: want{statusCode: http.StatusNotFound, contentType: "text/html; charset=utf-8"},
+		},
+		{
+			name: "malformed id",
+			args: args{id: "malformed"},
+			want: want{statusCode: http.StatusNotFound, contentType: "text/html; charset=utf-8"},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				module := photo.Module{
+					Repository: &photo.Repository{Clock: system.RealClock{}, Tm: tm},
+					Templates:  templates.TemplatesFileSystem,
+				}
+
+				request := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/photos/"+tt.args.id+tt.args.query, nil)
+				request.SetPathValue("id", tt.args.id)
+				for key, values := range tt.args.header {
+					request.Header[key] = values
+				}
+
+				recorder := httptest.NewRecorder()
+				module.Download(recorder, request)
+
+				response := recorder.Result()
+				if response.StatusCode != tt.want.statusCode {
+					t.Errorf("GET /photos/%v status = %v; want %v", tt.args.id, response.StatusCode, tt.want.statusCode)
+				}
+
+				if got := response.Header.Get("Content-Type"); got != tt.want.contentType {
+					t.Errorf("GET /photos/%v Content-Type = %v; want %v", tt.args.id, got, tt.want.contentType)
+				}
+
+				if got := response.Header.Get("Cache-Control"); got != tt.want.cacheControl {
+					t.Errorf("GET /photos/%v Cache-Control = %v; want %v", tt.args.id, got, tt.want.cacheControl)
+				}
+
+				if tt.want.body != "" && recorder.Body.String() != tt.want.body {
+					t.Errorf("GET /photos/%v body = %x; want %x", tt.args.id, recorder.Body.String(), tt.want.body)
+				}
+			})
+		})
+	}
+}
+
 func NewPhotoTestModule(initFncs ...sqlx.DatabaseInitFunc) webx.ModuleConstructor {
 	return func(db *sql.DB) (webx.Module, error) {
 		for _, fn := range initFncs {
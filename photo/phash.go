@@ -0,0 +1,78 @@
+package photo
+
+import (
+	"image"
+	"image/color"
+	"math/bits"
+)
+
+// SimilarityThreshold ist der größte Abstand zwischen den Wahrnehmungshashes zweier Photos, bei dem die Photos als ähnlich gelten.
+const SimilarityThreshold = 10
+
+// segmentMasks teilen einen Wahrnehmungshash in SimilarityThreshold + 1 Abschnitte.
+//
+// Unterscheiden sich zwei Hashes in höchstens SimilarityThreshold Bits, stimmen sie nach dem Schubfachprinzip in mindestens einem Abschnitt überein. Nur solche Photos müssen verglichen werden.
+var segmentMasks = func() []uint64 {
+	const count = SimilarityThreshold + 1
+
+	masks := make([]uint64, count)
+	offset := 0
+	for i := range masks {
+		width := 64 / count
+		if i < 64%count {
+			width++
+		}
+
+		masks[i] = (1<<width - 1) << offset
+		offset += width
+	}
+
+	return masks
+}()
+
+// DifferenceHash berechnet den Wahrnehmungshash (dHash) eines Bildes.
+//
+// Das Bild wird auf 9x8 Graustufen verkleinert. Jedes Bit des Hashes gibt an, ob ein Bildpunkt heller ist als sein rechter Nachbar. Ähnliche Bilder haben Hashes mit geringem Hamming-Abstand, auch wenn sie sich in Größe, Helligkeit oder Kompression unterscheiden.
+func DifferenceHash(img image.Image) uint64 {
+	const width, height = 9, 8
+
+	bounds := img.Bounds()
+	var gray [height][width]uint64
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+
+			// Mittelwert der Bildpunkte des Ausschnitts
+			var sum, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					sum += uint64(color.Gray16Model.Convert(img.At(sx, sy)).(color.Gray16).Y)
+					count++
+				}
+			}
+			gray[y][x] = sum / count
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// Distance liefert den Hamming-Abstand zweier Wahrnehmungshashes.
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -1,6 +1,29 @@
 package photo
 
+import "time"
+
+// PlaceholderUrl ist die URL des Bildes, das angezeigt wird, wenn ein Ding kein Photo hat.
+const PlaceholderUrl = "/static/placeholder.svg"
+
 type PhotoData struct {
 	Id       int64
 	PhotoUrl string
 }
+
+// HasPhoto ist wahr, wenn für das Ding ein Photo gespeichert ist.
+func (p PhotoData) HasPhoto() bool {
+	return p.PhotoUrl != PlaceholderUrl
+}
+
+// Photo ist ein in der Datenbank gespeichertes Photo eines Dings.
+type Photo struct {
+	Data         []byte
+	MimeType     string
+	Hash         string
+	Aktualisiert time.Time
+}
+
+// ETag liefert den Entity Tag des Photos für HTTP Antworten.
+func (p Photo) ETag() string {
+	return `"` + p.Hash + `"`
+}
@@ -2,6 +2,7 @@ package photo_test
 
 import (
 	"image"
+	"image/color"
 	_ "image/jpeg"
 	"reflect"
 	"testing"
@@ -63,3 +64,46 @@ func TestCrop(t *testing.T) {
 		})
 	}
 }
+
+func TestDifferenceHash(t *testing.T) {
+	gradient := func(width, height int, brightness uint8) image.Image {
+		img := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				img.SetGray(x, y, color.Gray{Y: uint8(x*200/width) + brightness})
+			}
+		}
+		return img
+	}
+
+	checkerboard := image.NewGray(image.Rect(0, 0, 90, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 90; x++ {
+			if (x/10+y/10)%2 == 0 {
+				checkerboard.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	original := photo.DifferenceHash(gradient(600, 400, 0))
+
+	tests := []struct {
+		name    string
+		img     image.Image
+		similar bool
+	}{
+		{name: "same image", img: gradient(600, 400, 0), similar: true},
+		{name: "resized", img: gradient(285, 190, 0), similar: true},
+		{name: "brighter", img: gradient(600, 400, 40), similar: true},
+		{name: "different image", img: checkerboard, similar: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := photo.Distance(original, photo.DifferenceHash(tt.img))
+			if (distance <= photo.SimilarityThreshold) != tt.similar {
+				t.Errorf("Distance() = %v; want similar %v", distance, tt.similar)
+			}
+		})
+	}
+}
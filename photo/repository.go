@@ -2,58 +2,90 @@ package photo
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"image"
+	_ "image/jpeg"
 	"image/png"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/webx"
 	"github.com/mattn/go-sqlite3"
 )
 
 type Repository struct {
-	Clock Clock
-	Tm    sqlx.TransactionManager
+	Clock   Clock
+	Tm      sqlx.TransactionManager
+	Storage BlobStorage
 }
 
-func (r Repository) GetPhotoById(ctx context.Context, dingId int64) ([]byte, error) {
+// storage liefert den Speicher für den Inhalt der Photos.
+//
+// Ohne ausdrücklich festgelegten Speicher werden die Inhalte wie bisher in der Datenbank gespeichert.
+func (r Repository) storage() BlobStorage {
+	if r.Storage == nil {
+		return SqliteStorage{Tm: r.Tm}
+	}
+	return r.Storage
+}
 
-	suchen := `SELECT photo FROM photos WHERE dinge_id = :id`
+// GetPhotoById liefert das Photo eines Dings mit MIME Type, Hash und Zeitpunkt der letzten Änderung.
+func (r Repository) GetPhotoById(ctx context.Context, dingId int64) (Photo, error) {
+
+	suchen := `
+	SELECT mime_type, hash, aktualisiert
+	FROM photos
+	WHERE dinge_id = :id`
 
 	tx, err := r.Tm.BeginTx(ctx)
 	if err != nil {
-		return nil, err
+		return Photo{}, err
 	}
 
 	defer tx.Rollback()
 
-	var photo []byte
+	var photo Photo
 	row := tx.QueryRowContext(suchen, sql.Named("id", dingId))
-	if err := row.Scan(&photo); err != nil {
+	if err := row.Scan(&photo.MimeType, &photo.Hash, &photo.Aktualisiert); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNoRecord
+			return photo, ErrNoRecord
 		}
 
 		return photo, err
 	}
+
+	data, err := r.storage().Get(ctx, photo.Hash)
+	if err != nil {
+		return photo, err
+	}
+
+	photo.Data = data
 	return photo, tx.Commit()
 }
 
 // GetUrl liefert die URL des Photos eines Dings.
 //
-// [id] ist die id eines Dings.
+// [id] ist die id eines Dings. Die URL enthält den Hash des Photos als Version, so dass Browser das Photo dauerhaft zwischenspeichern können.
 func (r Repository) GetUrl(ctx context.Context, dingId int64) (string, error) {
 
-	url := "/static/placeholder.svg"
+	url := PlaceholderUrl
 
 	if ctx == nil {
 		return url, errors.New("no context provided")
 	}
 
 	suchen := `
-	SELECT COUNT(rowid)
+	SELECT hash
 	FROM photos
 	WHERE dinge_id = :dingId
 	`
@@ -66,14 +98,15 @@ func (r Repository) GetUrl(ctx context.Context, dingId int64) (string, error) {
 	defer tx.Rollback()
 
 	row := tx.QueryRowContext(suchen, sql.Named("dingId", dingId))
-	var count int
-	if err := row.Scan(&count); err != nil {
+	var hash string
+	if err := row.Scan(&hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return url, tx.Commit()
+		}
 		return url, err
 	}
 
-	if count == 1 {
-		url = fmt.Sprintf("/photos/%v", dingId)
-	}
+	url = fmt.Sprintf("/photos/%v?v=%v", dingId, hash)
 
 	return url, tx.Commit()
 }
@@ -94,25 +127,61 @@ func (r Repository) PhotoAktualisieren(ctx context.Context, id int64, image imag
 	}
 
 	timestamp := r.Clock.Now()
+	sum := sha256.Sum256(buffer.Bytes())
+	hash := hex.EncodeToString(sum[:])
+
+	phash := DifferenceHash(thumbnail)
+
+	if err := r.savePhoto(ctx, id, hash, phash, buffer.Bytes(), timestamp); err != nil {
+		// Der Inhalt ist möglicherweise gespeichert, aber kein Photo verweist auf ihn.
+		return errors.Join(err, r.markGarbage(ctx, hash))
+	}
+
+	webx.LoggerFrom(ctx).Info("photo stored",
+		slog.Int64("ding", id),
+		slog.String("hash", hash),
+		slog.Int("bytes", buffer.Len()))
+
+	return nil
+}
+
+// savePhoto speichert das Photo eines Dings und seinen Inhalt data im BlobStorage.
+//
+// Ein externer Speicher erhält den Inhalt vor der Transaktion, damit die Datenbank während eines langsamen Uploads nicht für andere Schreibzugriffe gesperrt ist. Nur [SqliteStorage] speichert den Inhalt in der Zeile des Photos und damit in derselben Transaktion.
+func (r Repository) savePhoto(ctx context.Context, id int64, hash string, phash uint64, data []byte, timestamp time.Time) error {
+	storage := r.storage()
+	_, inline := storage.(SqliteStorage)
+
+	if !inline {
+		// CollectGarbage darf einen vorgemerkten Inhalt mit demselben Hash nicht entfernen, während er erneut gespeichert wird.
+		if err := r.exec(ctx, `DELETE FROM photo_garbage WHERE hash = :hash`, sql.Named("hash", hash)); err != nil {
+			return err
+		}
+
+		if err := storage.Put(ctx, hash, data); err != nil {
+			return err
+		}
+	}
 
 	statement := `
-	INSERT INTO photos(photo, mime_type, dinge_id)
-	VALUES(:photo, :mime_type, :id)
+	INSERT INTO photos(photo, mime_type, hash, phash, aktualisiert, dinge_id)
+	VALUES(NULL, :mime_type, :hash, :phash, :aktualisiert, :id)
 	ON CONFLICT (dinge_id)
-	DO UPDATE SET photo = :photo, mime_type = :mime_type;
+	DO UPDATE SET photo = NULL, mime_type = :mime_type, hash = :hash, phash = :phash, aktualisiert = :aktualisiert;
 	`
 
 	tx, err := r.Tm.BeginTx(ctx)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	defer tx.Rollback()
 
 	_, err = tx.ExecContext(statement,
 		sql.Named("id", id),
-		sql.Named("photo", buffer.Bytes()),
 		sql.Named("mime_type", "image/png"),
+		sql.Named("hash", hash),
+		sql.Named("phash", int64(phash)),
 		sql.Named("aktualisiert", timestamp))
 
 	if err != nil {
@@ -124,8 +193,452 @@ func (r Repository) PhotoAktualisieren(ctx context.Context, id int64, image imag
 		return err
 	}
 
+	if inline {
+		if err := storage.Put(ctx, hash, data); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// markGarbage merkt den Inhalt mit dem Hash hash für [Repository.CollectGarbage] vor.
+//
+// CollectGarbage entfernt den Inhalt nur, wenn kein Photo ihn verwendet.
+func (r Repository) markGarbage(ctx context.Context, hash string) error {
+	statement := `INSERT OR IGNORE INTO photo_garbage(hash) VALUES(:hash)`
+	return r.exec(ctx, statement, sql.Named("hash", hash))
+}
+
+// Similar liefert die Ids der Dinge, deren Photos dem Photo des Dings dingId ähnlich sehen.
+//
+// Die Dinge sind nach aufsteigendem Abstand der Wahrnehmungshashes sortiert. Hat das Ding kein Photo, ist das Ergebnis leer. Die Datenbank liefert nur Photos, die in mindestens einem Abschnitt des Wahrnehmungshashes übereinstimmen, siehe [segmentMasks].
+func (r Repository) Similar(ctx context.Context, dingId int64) ([]int64, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var stored int64
+	row := tx.QueryRowContext(`SELECT phash FROM photos WHERE dinge_id = :id AND phash IS NOT NULL`, sql.Named("id", dingId))
+	if err := row.Scan(&stored); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return []int64{}, nil
+		}
+		return nil, err
+	}
+
+	phash := uint64(stored)
+
+	// Die Masken und Werte sind Zahlen und werden direkt in die Abfrage eingesetzt.
+	conditions := make([]string, len(segmentMasks))
+	for i, mask := range segmentMasks {
+		conditions[i] = fmt.Sprintf("(phash & %d) = %d", int64(mask), int64(phash&mask))
+	}
+
+	suchen := fmt.Sprintf(`
+	SELECT dinge_id, phash
+	FROM photos
+	WHERE phash IS NOT NULL AND dinge_id <> :id AND (%v)
+	`, strings.Join(conditions, " OR "))
+
+	hashes, err := scanPerceptualHashes(tx, suchen, sql.Named("id", dingId))
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		id       int64
+		distance int
+	}
+
+	candidates := []candidate{}
+	for id, other := range hashes {
+		if distance := Distance(phash, other); distance <= SimilarityThreshold {
+			candidates = append(candidates, candidate{id: id, distance: distance})
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.id, b.id))
+	})
+
+	similar := []int64{}
+	for _, c := range candidates {
+		similar = append(similar, c.id)
+	}
+
+	return similar, tx.Commit()
+}
+
+// Duplicates liefert Paare von Ids der Dinge, deren Photos sich ähnlich sehen.
+//
+// Die kleinere Id steht in jedem Paar an erster Stelle. Die Paare sind nach Ids sortiert. Verglichen werden nur Photos, die in mindestens einem Abschnitt des Wahrnehmungshashes übereinstimmen, siehe [segmentMasks].
+func (r Repository) Duplicates(ctx context.Context) ([][2]int64, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	hashes, err := scanPerceptualHashes(tx, `SELECT dinge_id, phash FROM photos WHERE phash IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+
+	type segment struct {
+		index int
+		value uint64
+	}
+
+	buckets := map[segment][]int64{}
+	for _, id := range slices.Sorted(maps.Keys(hashes)) {
+		for i, mask := range segmentMasks {
+			key := segment{index: i, value: hashes[id] & mask}
+			buckets[key] = append(buckets[key], id)
+		}
+	}
+
+	found := map[[2]int64]bool{}
+	for _, ids := range buckets {
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				if Distance(hashes[a], hashes[b]) <= SimilarityThreshold {
+					found[[2]int64{a, b}] = true
+				}
+			}
+		}
+	}
+
+	pairs := slices.SortedFunc(maps.Keys(found), func(a, b [2]int64) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+
+	return pairs, tx.Commit()
+}
+
+// scanPerceptualHashes liefert die Wahrnehmungshashes der Abfrage query, die Id des Dings und den Hash liefert, zugeordnet zur Id des Dings.
+func scanPerceptualHashes(tx sqlx.Transaction, query string, args ...any) (map[int64]uint64, error) {
+	rows, err := tx.QueryContext(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hashes := map[int64]uint64{}
+	for rows.Next() {
+		var id, phash int64
+		if err := rows.Scan(&id, &phash); err != nil {
+			return nil, err
+		}
+		hashes[id] = uint64(phash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// BackfillPerceptualHashes berechnet die Wahrnehmungshashes von Photos, die ohne Wahrnehmungshash gespeichert wurden.
+//
+// Photos, deren Inhalt fehlt oder kein Bild ist, werden protokolliert und als versucht markiert, damit spätere Aufrufe sie übergehen. Das Ergebnis ist die Anzahl der ergänzten Photos.
+func (r Repository) BackfillPerceptualHashes(ctx context.Context) (int, error) {
+	missing, err := r.photosWithoutPerceptualHash(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Wurde das Photo inzwischen ersetzt, bleibt der neue Wahrnehmungshash erhalten.
+	statement := `UPDATE photos SET phash = :phash WHERE dinge_id = :id AND hash = :hash AND phash IS NULL`
+
+	filled := 0
+	for id, hash := range missing {
+		data, err := r.storage().Get(ctx, hash)
+		if err != nil {
+			if !errors.Is(err, ErrNoRecord) {
+				return filled, err
+			}
+
+			webx.LoggerFrom(ctx).Warn("photo content missing", slog.Int64("ding", id), slog.String("hash", hash))
+			if err := r.markAttempted(ctx, id, hash); err != nil {
+				return filled, err
+			}
+			continue
+		}
+
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			webx.LoggerFrom(ctx).Warn("decoding photo", slog.Int64("ding", id), slog.String("hash", hash), slog.String("error", err.Error()))
+			if err := r.markAttempted(ctx, id, hash); err != nil {
+				return filled, err
+			}
+			continue
+		}
+
+		phash := DifferenceHash(img)
+		if err := r.exec(ctx, statement, sql.Named("phash", int64(phash)), sql.Named("id", id), sql.Named("hash", hash)); err != nil {
+			return filled, err
+		}
+
+		filled++
+	}
+
+	return filled, nil
+}
+
+// markAttempted merkt sich, dass der Wahrnehmungshash des Photos mit dem Inhalt hash nicht berechnet werden konnte.
+func (r Repository) markAttempted(ctx context.Context, id int64, hash string) error {
+	statement := `UPDATE photos SET phash_versucht = :versucht WHERE dinge_id = :id AND hash = :hash AND phash IS NULL`
+	return r.exec(ctx, statement, sql.Named("versucht", r.Clock.Now()), sql.Named("id", id), sql.Named("hash", hash))
+}
+
+// photosWithoutPerceptualHash liefert die Hashes der Inhalte von Photos ohne Wahrnehmungshash, die noch nicht versucht wurden, zugeordnet zur Id des Dings.
+func (r Repository) photosWithoutPerceptualHash(ctx context.Context) (map[int64]string, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`SELECT dinge_id, hash FROM photos WHERE phash IS NULL AND phash_versucht IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	missing := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+		missing[id] = hash
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return missing, tx.Commit()
+}
+
+// exec führt die Anweisung statement in einer eigenen Transaktion aus.
+func (r Repository) exec(ctx context.Context, statement string, args ...any) error {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(statement, args...); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
+// PhotoEntfernen entfernt das Photo eines Dings.
+//
+// Der Inhalt des Photos wird anschließend von [Repository.CollectGarbage] aus dem Speicher entfernt.
+func (r Repository) PhotoEntfernen(ctx context.Context, dingId int64) error {
+	statement := `DELETE FROM photos WHERE dinge_id = :id`
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(statement, sql.Named("id", dingId))
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoRecord
+	}
+
+	return tx.Commit()
+}
+
+// MergePhotos überträgt das Photo des Dings otherId auf das Ding survivorId, wenn das Ding survivorId kein eigenes Photo besitzt.
+//
+// Besitzt das Ding survivorId bereits ein Photo, wird das Photo des Dings otherId entfernt. Der Inhalt wird später von [Repository.CollectGarbage] aus dem Speicher entfernt.
+func (r Repository) MergePhotos(ctx context.Context, survivorId int64, otherId int64) error {
+	statements := []string{
+		`DELETE FROM photos
+		WHERE dinge_id = :other
+		AND EXISTS (SELECT 1 FROM photos WHERE dinge_id = :survivor)`,
+		`UPDATE photos SET dinge_id = :survivor WHERE dinge_id = :other`,
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		_, err := tx.ExecContext(statement, sql.Named("survivor", survivorId), sql.Named("other", otherId))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CollectGarbage entfernt Photos, deren Ding nicht mehr existiert, und Inhalte, die von keinem Photo mehr verwendet werden.
+//
+// Wird ein Photo entfernt oder ersetzt, merkt sich die Datenbank den Hash des Inhalts in der Tabelle photo_garbage. CollectGarbage entfernt diese Inhalte aus dem Speicher, sofern kein anderes Photo den gleichen Inhalt verwendet. Hashes von Inhalten, die noch verwendet werden, bleiben vorgemerkt. Das Ergebnis ist die Anzahl der entfernten Inhalte.
+func (r Repository) CollectGarbage(ctx context.Context) (int, error) {
+	orphans := `DELETE FROM photos WHERE dinge_id NOT IN (SELECT id FROM dinge)`
+
+	candidates := `SELECT hash FROM photo_garbage ORDER BY hash`
+
+	// Ein Upload kann den Inhalt inzwischen wieder verwenden. Daher wird für jeden Hash erneut geprüft, ob er unbenutzt ist.
+	collect := `
+	DELETE FROM photo_garbage
+	WHERE hash = :hash
+	AND hash NOT IN (SELECT hash FROM photos)
+	`
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	// Die Transaktion schreibt zuerst und sperrt damit die Datenbank für andere Schreibzugriffe bis zum Commit.
+	if _, err := tx.ExecContext(orphans); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(candidates)
+	if err != nil {
+		return 0, err
+	}
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, hash := range hashes {
+		result, err := tx.ExecContext(collect, sql.Named("hash", hash))
+		if err != nil {
+			return 0, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if affected == 0 {
+			continue
+		}
+
+		if err := r.storage().Delete(ctx, hash); err != nil {
+			return 0, err
+		}
+
+		removed++
+	}
+
+	return removed, tx.Commit()
+}
+
+// MigrateStorage verschiebt die Inhalte aller Photos aus dem Speicher from in den Speicher to.
+//
+// Ein Inhalt wird erst aus from entfernt, nachdem er in to gespeichert wurde. Eine abgebrochene Migration kann daher erneut gestartet werden. Das Ergebnis ist die Anzahl der verschobenen Inhalte.
+func (r Repository) MigrateStorage(ctx context.Context, from BlobStorage, to BlobStorage) (int, error) {
+	hashes, err := r.hashes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, hash := range hashes {
+		data, err := from.Get(ctx, hash)
+		if err != nil {
+			if errors.Is(err, ErrNoRecord) {
+				// Bereits verschoben
+				continue
+			}
+			return moved, err
+		}
+
+		if err := to.Put(ctx, hash, data); err != nil {
+			return moved, err
+		}
+
+		if err := from.Delete(ctx, hash); err != nil {
+			return moved, err
+		}
+
+		moved++
+	}
+
+	return moved, nil
+}
+
+// hashes liefert die Hashes aller Photos.
+func (r Repository) hashes(ctx context.Context) ([]string, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`SELECT DISTINCT hash FROM photos ORDER BY hash`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return hashes, err
+	}
+
+	return hashes, tx.Commit()
+}
+
 var ErrNoRecord = errors.New("no record found")
 var ErrInvalidParameter = errors.New("invalid paramater")
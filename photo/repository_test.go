@@ -7,6 +7,9 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"io/fs"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -202,3 +205,504 @@ func must[T any](value T, err error) T {
 func theFixture(db *sql.DB) error {
 	return sqlx.ExecuteScripts(db, ding.CreateScript, photo.CreateScript, ding.FixtureScript, photo.FixtureScript)
 }
+
+// failingStorage speichert Inhalte und meldet anschließend einen Fehler, wie ein abgebrochener Upload.
+type failingStorage struct {
+	photo.FilesystemStorage
+}
+
+func (s failingStorage) Put(ctx context.Context, key string, data []byte) error {
+	if err := s.FilesystemStorage.Put(ctx, key, data); err != nil {
+		return err
+	}
+	return errors.New("connection reset")
+}
+
+func TestRepository_PhotoAktualisieren_Failed(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		storage := photo.FilesystemStorage{Dir: t.TempDir()}
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm, Storage: failingStorage{storage}}
+
+		img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+		if err := repository.PhotoAktualisieren(ctx, 1, img); err == nil {
+			t.Fatal("Repository.PhotoAktualisieren() error = nil; want error")
+		}
+
+		if count := tm.Count(); count != 0 {
+			t.Errorf("TransactionManager.Count() = %v; want 0", count)
+		}
+
+		// Das Photo ist unverändert, der verwaiste Inhalt wird bei der nächsten Garbage Collection entfernt.
+		removed, err := repository.CollectGarbage(ctx)
+		if err != nil || removed != 1 {
+			t.Errorf("Repository.CollectGarbage() = %v, %v; want 1, nil", removed, err)
+		}
+
+		err = filepath.WalkDir(storage.Dir, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				t.Errorf("stored content %v; want none", entry.Name())
+			}
+			return err
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// hookStorage ruft nach dem Speichern eines Inhalts die Funktion after auf, zum Beispiel um einen langsamen Upload nachzustellen.
+type hookStorage struct {
+	photo.FilesystemStorage
+	after func()
+}
+
+func (s hookStorage) Put(ctx context.Context, key string, data []byte) error {
+	if err := s.FilesystemStorage.Put(ctx, key, data); err != nil {
+		return err
+	}
+
+	s.after()
+	return nil
+}
+
+func TestRepository_PhotoAktualisieren_Upload(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		storage := photo.FilesystemStorage{Dir: t.TempDir()}
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm, Storage: storage}
+
+		img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+		if err := repository.PhotoAktualisieren(ctx, 1, img); err != nil {
+			t.Fatalf("Repository.PhotoAktualisieren() error = %v", err)
+		}
+
+		// Der Inhalt ist nach dem Entfernen des Photos für die Garbage Collection vorgemerkt.
+		if err := repository.PhotoEntfernen(ctx, 1); err != nil {
+			t.Fatalf("Repository.PhotoEntfernen() error = %v", err)
+		}
+
+		// Während des erneuten Uploads ist keine Transaktion offen und die Garbage Collection läuft.
+		repository.Storage = hookStorage{storage, func() {
+			if count := tm.Count(); count != 0 {
+				t.Errorf("TransactionManager.Count() during upload = %v; want 0", count)
+			}
+
+			type collectKey string
+			if _, err := repository.CollectGarbage(context.WithValue(ctx, collectKey("collect"), true)); err != nil {
+				t.Errorf("Repository.CollectGarbage() error = %v", err)
+			}
+		}}
+
+		if err := repository.PhotoAktualisieren(ctx, 1, img); err != nil {
+			t.Fatalf("Repository.PhotoAktualisieren() error = %v", err)
+		}
+
+		got, err := repository.GetPhotoById(ctx, 1)
+		if err != nil {
+			t.Fatalf("Repository.GetPhotoById() error = %v", err)
+		}
+
+		if _, err := storage.Get(ctx, got.Hash); err != nil {
+			t.Errorf("BlobStorage.Get(%v) error = %v; want stored content", got.Hash, err)
+		}
+	})
+}
+
+func TestRepository_PhotoAktualisieren_UnknownDing(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		storage := photo.FilesystemStorage{Dir: t.TempDir()}
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm, Storage: storage}
+
+		img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+		if err := repository.PhotoAktualisieren(ctx, 42, img); !errors.Is(err, photo.ErrNoRecord) {
+			t.Fatalf("Repository.PhotoAktualisieren() error = %v; want %v", err, photo.ErrNoRecord)
+		}
+
+		// Der bereits gespeicherte Inhalt wird bei der nächsten Garbage Collection entfernt.
+		removed, err := repository.CollectGarbage(ctx)
+		if err != nil || removed != 1 {
+			t.Errorf("Repository.CollectGarbage() = %v, %v; want 1, nil", removed, err)
+		}
+	})
+}
+
+func TestRepository_PhotoEntfernen(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int64
+		wantErr error
+	}{
+		{name: "remove existing photo", id: 1, wantErr: nil},
+		{name: "remove unknown photo", id: 42, wantErr: photo.ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+
+				err := repository.PhotoEntfernen(context.Background(), tt.id)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Repository.PhotoEntfernen() error = %v, want %v", err, tt.wantErr)
+				}
+
+				if _, err := repository.GetPhotoById(context.Background(), tt.id); !errors.Is(err, photo.ErrNoRecord) {
+					t.Errorf("Repository.GetPhotoById() error = %v, want %v", err, photo.ErrNoRecord)
+				}
+
+				url, err := repository.GetUrl(context.Background(), tt.id)
+				if err != nil || url != photo.PlaceholderUrl {
+					t.Errorf("Repository.GetUrl() = %v, %v; want %v, nil", url, err, photo.PlaceholderUrl)
+				}
+			})
+		})
+	}
+}
+
+func TestRepository_CollectGarbage(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		storage := photo.FilesystemStorage{Dir: t.TempDir()}
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm, Storage: storage}
+
+		if _, err := repository.MigrateStorage(ctx, photo.SqliteStorage{Tm: tm}, storage); err != nil {
+			t.Fatal(err)
+		}
+
+		// Photo 1 wird entfernt, Photo 2 wird ersetzt.
+		if err := repository.PhotoEntfernen(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+		if err := repository.PhotoAktualisieren(ctx, 2, img); err != nil {
+			t.Fatal(err)
+		}
+
+		removed, err := repository.CollectGarbage(ctx)
+		if err != nil {
+			t.Fatalf("Repository.CollectGarbage() error = %v", err)
+		}
+
+		if removed != 2 {
+			t.Errorf("Repository.CollectGarbage() = %v; want %v", removed, 2)
+		}
+
+		for _, hash := range []string{
+			"db24abe2d0a20fac957859eb755b3dbf695becdb9f5890e3a2a2411273ae50ad",
+			"6c450e037e79b76f231a71a22ff40403f7d9b74b15e014e52fe1156d3666c3e6",
+		} {
+			if _, err := storage.Get(ctx, hash); !errors.Is(err, photo.ErrNoRecord) {
+				t.Errorf("BlobStorage.Get(%v) error = %v; want %v", hash, err, photo.ErrNoRecord)
+			}
+		}
+
+		for _, id := range []int64{2, 3} {
+			if _, err := repository.GetPhotoById(ctx, id); err != nil {
+				t.Errorf("Repository.GetPhotoById(%v) error = %v", id, err)
+			}
+		}
+
+		removed, err = repository.CollectGarbage(ctx)
+		if err != nil || removed != 0 {
+			t.Errorf("Repository.CollectGarbage() repeated = %v, %v; want 0, nil", removed, err)
+		}
+	})
+}
+
+func TestRepository_CollectGarbage_Orphans(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		db := tm.(*sqlx.SqlTransactionManager).GetDb()
+
+		// Ein Ding ohne Fremdschlüsselprüfung entfernen, wie es zum Beispiel ältere Versionen der Datenbank zulassen.
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF; DELETE FROM dinge WHERE id = 3; PRAGMA foreign_keys = ON;`); err != nil {
+			t.Fatal(err)
+		}
+
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+		removed, err := repository.CollectGarbage(ctx)
+		if err != nil {
+			t.Fatalf("Repository.CollectGarbage() error = %v", err)
+		}
+
+		if removed != 1 {
+			t.Errorf("Repository.CollectGarbage() = %v; want %v", removed, 1)
+		}
+
+		if _, err := repository.GetPhotoById(ctx, 3); !errors.Is(err, photo.ErrNoRecord) {
+			t.Errorf("Repository.GetPhotoById() error = %v, want %v", err, photo.ErrNoRecord)
+		}
+	})
+}
+
+func TestRepository_CollectGarbage_HashInUse(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		db := tm.(*sqlx.SqlTransactionManager).GetDb()
+		storage := photo.FilesystemStorage{Dir: t.TempDir()}
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm, Storage: storage}
+
+		if _, err := repository.MigrateStorage(ctx, photo.SqliteStorage{Tm: tm}, storage); err != nil {
+			t.Fatal(err)
+		}
+
+		// Der Inhalt von Photo 2 ist vorgemerkt, wird aber noch verwendet, zum Beispiel nach einem erneuten Upload.
+		hash := "6c450e037e79b76f231a71a22ff40403f7d9b74b15e014e52fe1156d3666c3e6"
+		if _, err := db.Exec(`INSERT INTO photo_garbage(hash) VALUES(?)`, hash); err != nil {
+			t.Fatal(err)
+		}
+
+		removed, err := repository.CollectGarbage(ctx)
+		if err != nil || removed != 0 {
+			t.Fatalf("Repository.CollectGarbage() = %v, %v; want 0, nil", removed, err)
+		}
+
+		if _, err := storage.Get(ctx, hash); err != nil {
+			t.Fatalf("BlobStorage.Get(%v) error = %v", hash, err)
+		}
+
+		// Der Hash bleibt vorgemerkt, bis kein Photo den Inhalt mehr verwendet.
+		var queued int
+		if err := db.QueryRow(`SELECT count(*) FROM photo_garbage WHERE hash = ?`, hash).Scan(&queued); err != nil || queued != 1 {
+			t.Fatalf("photo_garbage count = %v, %v; want 1, nil", queued, err)
+		}
+
+		if _, err := db.Exec(`DELETE FROM photos WHERE hash = ?`, hash); err != nil {
+			t.Fatal(err)
+		}
+
+		removed, err = repository.CollectGarbage(ctx)
+		if err != nil || removed != 1 {
+			t.Errorf("Repository.CollectGarbage() = %v, %v; want 1, nil", removed, err)
+		}
+
+		if _, err := storage.Get(ctx, hash); !errors.Is(err, photo.ErrNoRecord) {
+			t.Errorf("BlobStorage.Get(%v) error = %v; want %v", hash, err, photo.ErrNoRecord)
+		}
+	})
+}
+
+func TestRepository_Similar(t *testing.T) {
+	tests := []struct {
+		name string
+		id   int64
+		want []int64
+	}{
+		{name: "similar photo", id: 1, want: []int64{2}},
+		{name: "no similar photo", id: 3, want: []int64{}},
+		{name: "without photo", id: 42, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+
+				got, err := repository.Similar(context.Background(), tt.id)
+				if err != nil {
+					t.Fatalf("Repository.Similar() error = %v", err)
+				}
+
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Repository.Similar() = %v; want %v", got, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestRepository_Duplicates(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+
+		got, err := repository.Duplicates(ctx)
+		if err != nil {
+			t.Fatalf("Repository.Duplicates() error = %v", err)
+		}
+
+		want := [][2]int64{{1, 2}}
+		if !slices.Equal(got, want) {
+			t.Errorf("Repository.Duplicates() = %v; want %v", got, want)
+		}
+
+		// Das gleiche Photo für zwei Dinge
+		img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+		draw.Draw(img, image.Rect(0, 0, 200, 400), &image.Uniform{color.White}, image.Point{}, draw.Src)
+		for _, id := range []int64{1, 3} {
+			if err := repository.PhotoAktualisieren(ctx, id, img); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err = repository.Duplicates(ctx)
+		if err != nil {
+			t.Fatalf("Repository.Duplicates() error = %v", err)
+		}
+
+		want = [][2]int64{{1, 3}}
+		if !slices.Equal(got, want) {
+			t.Errorf("Repository.Duplicates() = %v; want %v", got, want)
+		}
+	})
+}
+
+func TestRepository_Similar_Threshold(t *testing.T) {
+	// Jedes Bit liegt in einem anderen Abschnitt des Wahrnehmungshashes.
+	bits := func(count int) int64 {
+		var phash uint64
+		for i := range count {
+			phash |= 1 << (i * 6)
+		}
+		return int64(phash)
+	}
+
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		db := tm.(*sqlx.SqlTransactionManager).GetDb()
+
+		for id, phash := range map[int64]int64{1: 0, 2: bits(photo.SimilarityThreshold), 3: bits(photo.SimilarityThreshold + 1)} {
+			if _, err := db.Exec(`UPDATE photos SET phash = ? WHERE dinge_id = ?`, phash, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+
+		got, err := repository.Similar(ctx, 1)
+		if err != nil || !slices.Equal(got, []int64{2}) {
+			t.Errorf("Repository.Similar() = %v, %v; want %v", got, err, []int64{2})
+		}
+
+		pairs, err := repository.Duplicates(ctx)
+		want := [][2]int64{{1, 2}, {2, 3}}
+		if err != nil || !slices.Equal(pairs, want) {
+			t.Errorf("Repository.Duplicates() = %v, %v; want %v", pairs, err, want)
+		}
+	})
+}
+
+func TestRepository_BackfillPerceptualHashes(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		db := tm.(*sqlx.SqlTransactionManager).GetDb()
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+
+		img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+		draw.Draw(img, image.Rect(0, 0, 200, 400), &image.Uniform{color.White}, image.Point{}, draw.Src)
+		if err := repository.PhotoAktualisieren(ctx, 1, img); err != nil {
+			t.Fatal(err)
+		}
+
+		var want int64
+		if err := db.QueryRow(`SELECT phash FROM photos WHERE dinge_id = 1`).Scan(&want); err != nil {
+			t.Fatal(err)
+		}
+
+		// Photos älterer Versionen haben keinen Wahrnehmungshash. Die Inhalte der Photos 2 und 3 sind keine Bilder.
+		if _, err := db.Exec(`UPDATE photos SET phash = NULL`); err != nil {
+			t.Fatal(err)
+		}
+
+		filled, err := repository.BackfillPerceptualHashes(ctx)
+		if err != nil || filled != 1 {
+			t.Fatalf("Repository.BackfillPerceptualHashes() = %v, %v; want 1, nil", filled, err)
+		}
+
+		var got int64
+		if err := db.QueryRow(`SELECT phash FROM photos WHERE dinge_id = 1`).Scan(&got); err != nil || got != want {
+			t.Errorf("phash = %v, %v; want %v", got, err, want)
+		}
+
+		// Die Photos 2 und 3 werden nur einmal versucht.
+		var attempted int
+		if err := db.QueryRow(`SELECT count(*) FROM photos WHERE phash IS NULL AND phash_versucht IS NOT NULL`).Scan(&attempted); err != nil || attempted != 2 {
+			t.Errorf("attempted photos = %v, %v; want 2", attempted, err)
+		}
+
+		filled, err = repository.BackfillPerceptualHashes(ctx)
+		if err != nil || filled != 0 {
+			t.Errorf("Repository.BackfillPerceptualHashes() repeated = %v, %v; want 0, nil", filled, err)
+		}
+
+		if count := tm.Count(); count != 0 {
+			t.Errorf("TransactionManager.Count() = %v; want 0", count)
+		}
+	})
+}
+
+func TestRepository_MergePhotos(t *testing.T) {
+	tests := []struct {
+		name     string
+		remove   []int64
+		wantHash string
+	}{
+		{
+			name:     "survivor keeps own photo",
+			wantHash: "db24abe2d0a20fac957859eb755b3dbf695becdb9f5890e3a2a2411273ae50ad",
+		},
+		{
+			name:     "survivor takes photo of other",
+			remove:   []int64{1},
+			wantHash: "6c450e037e79b76f231a71a22ff40403f7d9b74b15e014e52fe1156d3666c3e6",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				ctx := context.Background()
+				repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+
+				for _, id := range tt.remove {
+					if err := repository.PhotoEntfernen(ctx, id); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if err := repository.MergePhotos(ctx, 1, 2); err != nil {
+					t.Fatalf("Repository.MergePhotos() error = %v", err)
+				}
+
+				got, err := repository.GetPhotoById(ctx, 1)
+				if err != nil {
+					t.Fatalf("Repository.GetPhotoById() error = %v", err)
+				}
+
+				if got.Hash != tt.wantHash {
+					t.Errorf("Repository.GetPhotoById().Hash = %v; want %v", got.Hash, tt.wantHash)
+				}
+
+				if _, err := repository.GetPhotoById(ctx, 2); !errors.Is(err, photo.ErrNoRecord) {
+					t.Errorf("Repository.GetPhotoById() of other error = %v; want %v", err, photo.ErrNoRecord)
+				}
+			})
+		})
+	}
+}
+
+func TestRepository_DingDeleted(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		db := tm.(*sqlx.SqlTransactionManager).GetDb()
+
+		// Wird ein Ding endgültig gelöscht, entfernt der Fremdschlüssel auch sein Photo.
+		if _, err := db.Exec(`DELETE FROM history WHERE dinge_id = 3; DELETE FROM dinge WHERE id = 3`); err != nil {
+			t.Fatal(err)
+		}
+
+		repository := &photo.Repository{Clock: system.RealClock{}, Tm: tm}
+		if _, err := repository.GetPhotoById(ctx, 3); !errors.Is(err, photo.ErrNoRecord) {
+			t.Errorf("Repository.GetPhotoById() error = %v, want %v", err, photo.ErrNoRecord)
+		}
+
+		removed, err := repository.CollectGarbage(ctx)
+		if err != nil || removed != 1 {
+			t.Errorf("Repository.CollectGarbage() = %v, %v; want 1, nil", removed, err)
+		}
+	})
+}
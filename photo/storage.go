@@ -0,0 +1,112 @@
+package photo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/haschi/dinge/sqlx"
+)
+
+// BlobStorage speichert den Inhalt von Photos.
+//
+// Die Inhalte werden über ihren Hash adressiert. Ein Inhalt, der unter einem Schlüssel gespeichert wurde, ändert sich nicht mehr. Die Metadaten eines Photos, wie der MIME Type oder das zugehörige Ding, verbleiben in der Tabelle photos.
+type BlobStorage interface {
+	// Put speichert data unter dem Schlüssel key.
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get liefert den unter dem Schlüssel key gespeicherten Inhalt. Ist kein Inhalt vorhanden, liefert Get [ErrNoRecord].
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete entfernt den unter dem Schlüssel key gespeicherten Inhalt. Ist kein Inhalt vorhanden, ist das kein Fehler.
+	Delete(ctx context.Context, key string) error
+}
+
+// SqliteStorage speichert den Inhalt der Photos in der Spalte photo der Tabelle photos.
+//
+// Da der Inhalt in der Zeile des Photos gespeichert wird, muss die Zeile vor dem Aufruf von [SqliteStorage.Put] existieren.
+type SqliteStorage struct {
+	Tm sqlx.TransactionManager
+}
+
+func (s SqliteStorage) Put(ctx context.Context, key string, data []byte) error {
+	statement := `UPDATE photos SET photo = :photo WHERE hash = :hash`
+
+	tx, err := s.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(statement, sql.Named("photo", data), sql.Named("hash", key))
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("no photo with hash %v: %w", key, ErrNoRecord)
+	}
+
+	return tx.Commit()
+}
+
+func (s SqliteStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	suchen := `
+	SELECT photo
+	FROM photos
+	WHERE hash = :hash AND photo IS NOT NULL
+	LIMIT 1`
+
+	tx, err := s.Tm.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var data []byte
+	row := tx.QueryRowContext(suchen, sql.Named("hash", key))
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return data, tx.Commit()
+}
+
+func (s SqliteStorage) Delete(ctx context.Context, key string) error {
+	statement := `UPDATE photos SET photo = NULL WHERE hash = :hash`
+
+	tx, err := s.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(statement, sql.Named("hash", key)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var keyPattern = regexp.MustCompile("^[0-9a-f]{3,128}$")
+
+// validateKey stellt sicher, dass ein Schlüssel ein hexadezimaler Hash ist und gefahrlos als Dateiname oder Objektname verwendet werden kann.
+func validateKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("malformed key %q: %w", key, ErrInvalidParameter)
+	}
+	return nil
+}
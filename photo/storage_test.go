@@ -0,0 +1,201 @@
+package photo_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/photo"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+)
+
+func TestBlobStorage(t *testing.T) {
+
+	const key = "db24abe2d0a20fac957859eb755b3dbf695becdb9f5890e3a2a2411273ae50ad"
+
+	storages := []struct {
+		name    string
+		storage func(t *testing.T, tm sqlx.TransactionManager) photo.BlobStorage
+	}{
+		{
+			name: "sqlite",
+			storage: func(t *testing.T, tm sqlx.TransactionManager) photo.BlobStorage {
+				return photo.SqliteStorage{Tm: tm}
+			},
+		},
+		{
+			name: "filesystem",
+			storage: func(t *testing.T, tm sqlx.TransactionManager) photo.BlobStorage {
+				return photo.FilesystemStorage{Dir: t.TempDir()}
+			},
+		},
+		{
+			name: "s3",
+			storage: func(t *testing.T, tm sqlx.TransactionManager) photo.BlobStorage {
+				server := httptest.NewServer(NewObjectStore("dinge", "access"))
+				t.Cleanup(server.Close)
+
+				return photo.S3Storage{
+					Endpoint:  server.URL,
+					Bucket:    "dinge",
+					Region:    "us-east-1",
+					AccessKey: "access",
+					SecretKey: "secret",
+					Clock:     system.RealClock{},
+					Client:    server.Client(),
+				}
+			},
+		},
+	}
+
+	for _, s := range storages {
+		t.Run(s.name, func(t *testing.T) {
+			withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+				ctx := context.Background()
+				storage := s.storage(t, tm)
+
+				if err := storage.Put(ctx, key, []byte("content")); err != nil {
+					t.Fatalf("Put() error = %v", err)
+				}
+
+				got, err := storage.Get(ctx, key)
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+
+				if string(got) != "content" {
+					t.Errorf("Get() = %v; want %v", string(got), "content")
+				}
+
+				if err := storage.Delete(ctx, key); err != nil {
+					t.Fatalf("Delete() error = %v", err)
+				}
+
+				if _, err := storage.Get(ctx, key); !errors.Is(err, photo.ErrNoRecord) {
+					t.Errorf("Get() after Delete() error = %v; want %v", err, photo.ErrNoRecord)
+				}
+
+				if err := storage.Delete(ctx, key); err != nil {
+					t.Errorf("Delete() of missing content error = %v; want nil", err)
+				}
+			})
+		})
+	}
+}
+
+func TestFilesystemStorage_MalformedKey(t *testing.T) {
+	storage := photo.FilesystemStorage{Dir: t.TempDir()}
+
+	for _, key := range []string{"", "../../etc/passwd", "ABC", "xy"} {
+		if err := storage.Put(context.Background(), key, []byte("content")); !errors.Is(err, photo.ErrInvalidParameter) {
+			t.Errorf("Put(%q) error = %v; want %v", key, err, photo.ErrInvalidParameter)
+		}
+	}
+}
+
+func TestRepository_MigrateStorage(t *testing.T) {
+	withTransactionManager(t, theFixture, func(t *testing.T, tm sqlx.TransactionManager) {
+		ctx := context.Background()
+		from := photo.SqliteStorage{Tm: tm}
+		to := photo.FilesystemStorage{Dir: t.TempDir()}
+
+		repository := photo.Repository{Clock: system.RealClock{}, Tm: tm, Storage: from}
+
+		moved, err := repository.MigrateStorage(ctx, from, to)
+		if err != nil {
+			t.Fatalf("Repository.MigrateStorage() error = %v", err)
+		}
+
+		if moved != 3 {
+			t.Errorf("Repository.MigrateStorage() = %v; want %v", moved, 3)
+		}
+
+		repository.Storage = to
+		got, err := repository.GetPhotoById(ctx, 1)
+		if err != nil {
+			t.Fatalf("Repository.GetPhotoById() error = %v", err)
+		}
+
+		if string(got.Data) != "\x01\x23\x45\x67\x89" {
+			t.Errorf("Repository.GetPhotoById() = %x; want %x", got.Data, "\x01\x23\x45\x67\x89")
+		}
+
+		moved, err = repository.MigrateStorage(ctx, from, to)
+		if err != nil || moved != 0 {
+			t.Errorf("Repository.MigrateStorage() repeated = %v, %v; want 0, nil", moved, err)
+		}
+
+		if repository.Tm.Count() != 0 {
+			t.Errorf("TransactionManager.Count() = %v; want %v", repository.Tm.Count(), 0)
+		}
+	})
+}
+
+// ObjectStore ist ein minimaler S3 kompatibler Object Store für Tests.
+//
+// Er unterstützt PUT, GET und DELETE für Objekte eines Buckets im Path-Style und prüft, ob die Anfragen signiert sind.
+type ObjectStore struct {
+	mu        sync.Mutex
+	bucket    string
+	accessKey string
+	objects   map[string][]byte
+}
+
+func NewObjectStore(bucket string, accessKey string) *ObjectStore {
+	return &ObjectStore{
+		bucket:    bucket,
+		accessKey: accessKey,
+		objects:   map[string][]byte{},
+	}
+}
+
+func (s *ObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	if _, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date")); err != nil {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	name, found := strings.CutPrefix(r.URL.Path, "/"+s.bucket+"/")
+	if !found {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[name] = data
+	case http.MethodGet:
+		data, ok := s.objects[name]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,53 @@
+-- Aktualisiert eine Datenbank, die mit einer früheren Version von create.sql angelegt wurde.
+--
+-- Das Skript darf nur einmal ausgeführt werden. Mit sqlite3 -bail bleibt die Datenbank unverändert, wenn eine Anweisung fehlschlägt.
+BEGIN;
+
+-- SQLite kann die Bedingungen einer Spalte nicht ändern. Die Tabelle wird daher neu angelegt.
+ALTER TABLE photos RENAME TO photos_upgrade;
+
+CREATE TABLE photos(
+  photo BLOB,
+  mime_type VARCHAR(100) NOT NULL,
+  hash VARCHAR(64) NOT NULL,
+  phash INTEGER,
+  -- Zeitpunkt, zu dem der Wahrnehmungshash nicht nachgetragen werden konnte. Siehe Repository.BackfillPerceptualHashes
+  phash_versucht DATETIME,
+  aktualisiert DATETIME NOT NULL,
+  -- Wird ein Ding endgültig gelöscht, wird auch sein Photo entfernt.
+  dinge_id INTEGER NOT NULL REFERENCES dinge ON DELETE CASCADE
+);
+
+-- SQLite kann keinen SHA-256 Hash berechnen. Bestehende Photos erhalten stattdessen einen zufälligen Schlüssel, der ihren Inhalt ebenso eindeutig bezeichnet.
+-- Ihr Perceptual Hash bleibt leer, bis ihn Repository.BackfillPerceptualHashes nachträgt. Als Zeitpunkt der Aktualisierung gilt der des Dings.
+INSERT INTO photos(photo, mime_type, hash, phash, aktualisiert, dinge_id)
+SELECT photos_upgrade.photo,
+  photos_upgrade.mime_type,
+  lower(hex(randomblob(32))),
+  NULL,
+  coalesce(dinge.aktualisiert, CURRENT_TIMESTAMP),
+  photos_upgrade.dinge_id
+FROM photos_upgrade
+  LEFT JOIN dinge ON dinge.id = photos_upgrade.dinge_id;
+
+DROP TABLE photos_upgrade;
+
+CREATE UNIQUE INDEX idx_photos_dinge_id ON photos(dinge_id);
+CREATE INDEX idx_photos_hash ON photos(hash);
+
+CREATE TABLE photo_garbage(
+  hash VARCHAR(64) NOT NULL PRIMARY KEY
+);
+
+CREATE TRIGGER trg_photos_delete AFTER DELETE ON photos
+BEGIN
+  INSERT OR IGNORE INTO photo_garbage(hash) VALUES(old.hash);
+END;
+
+CREATE TRIGGER trg_photos_update AFTER UPDATE OF hash ON photos
+WHEN old.hash <> new.hash
+BEGIN
+  INSERT OR IGNORE INTO photo_garbage(hash) VALUES(old.hash);
+END;
+
+COMMIT;
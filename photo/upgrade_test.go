@@ -0,0 +1,113 @@
+package photo_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/photo"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+	"github.com/haschi/dinge/testx"
+)
+
+// legacyScript legt die Tabelle photos an, wie sie vor der Einführung von photo/upgrade.sql angelegt wurde.
+const legacyScript = `
+CREATE TABLE photos(
+  photo BLOB NOT NULL,
+  mime_type VARCHAR(100) NOT NULL,
+  dinge_id INTEGER NOT NULL REFERENCES dinge
+);
+
+CREATE UNIQUE INDEX idx_photos_dinge_id ON photos(dinge_id);
+
+INSERT INTO photos(photo, mime_type, dinge_id)
+VALUES (X'0123456789', 'image/png', 1),
+  (X'1234567890', 'image/jpeg', 2);
+`
+
+func TestUpgradeScript(t *testing.T) {
+	want := schemaOf(t, ding.CreateScript, photo.CreateScript)
+
+	db, err := sqlx.NewTestDatabase(ding.CreateScript, ding.FixtureScript, legacyScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+	db.SetMaxOpenConns(0)
+
+	if _, err := db.Exec(photo.UpgradeScript); err != nil {
+		t.Fatalf("upgrade error = %v", err)
+	}
+
+	got, err := testx.Schema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("schema after upgrade = %v; want %v", got, want)
+	}
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	r := photo.Repository{Clock: system.RealClock{}, Tm: tm}
+
+	first, err := r.GetPhotoById(ctx, 1)
+	if err != nil {
+		t.Fatalf("Repository.GetPhotoById() error = %v", err)
+	}
+
+	if !bytes.Equal(first.Data, []byte{0x01, 0x23, 0x45, 0x67, 0x89}) || first.MimeType != "image/png" {
+		t.Errorf("Repository.GetPhotoById() = %v, %v; want legacy content", first.Data, first.MimeType)
+	}
+
+	second, err := r.GetPhotoById(ctx, 2)
+	if err != nil {
+		t.Fatalf("Repository.GetPhotoById() error = %v", err)
+	}
+
+	if len(first.Hash) != 64 || first.Hash == second.Hash || first.Aktualisiert.IsZero() {
+		t.Errorf("Repository.GetPhotoById() hash = %v, %v, aktualisiert = %v; want distinct keys and a timestamp", first.Hash, second.Hash, first.Aktualisiert)
+	}
+
+	url, err := r.GetUrl(ctx, 1)
+	if err != nil || !strings.HasSuffix(url, "?v="+first.Hash) {
+		t.Errorf("Repository.GetUrl() = %v, %v; want version %v", url, err, first.Hash)
+	}
+
+	if err := r.PhotoEntfernen(ctx, 1); err != nil {
+		t.Fatalf("Repository.PhotoEntfernen() error = %v", err)
+	}
+
+	if count, err := r.CollectGarbage(ctx); err != nil || count != 1 {
+		t.Errorf("Repository.CollectGarbage() = %v, %v; want 1", count, err)
+	}
+}
+
+// schemaOf liefert das Schema einer Datenbank, die mit scripts angelegt wurde.
+func schemaOf(t *testing.T, scripts ...string) []string {
+	t.Helper()
+
+	db, err := sqlx.NewTestDatabase(scripts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	schema, err := testx.Schema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
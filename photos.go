@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/haschi/dinge/photo"
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+	"github.com/haschi/dinge/webx"
+)
+
+// storageConfig beschreibt, wo der Inhalt der Photos gespeichert wird.
+type storageConfig struct {
+	Kind        string
+	Dir         string
+	S3Endpoint  string
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// newStorageConfig registriert die Parameter für den Speicher der Photos.
+func newStorageConfig(flags *flag.FlagSet, environment func(string) (string, bool)) *storageConfig {
+	config := &storageConfig{
+		Kind:        environmentOrDefault(environment, "PHOTO_STORAGE", "sqlite"),
+		Dir:         environmentOrDefault(environment, "PHOTO_DIR", "photos"),
+		S3Endpoint:  environmentOrDefault(environment, "S3_ENDPOINT", ""),
+		S3Bucket:    environmentOrDefault(environment, "S3_BUCKET", "dinge"),
+		S3Region:    environmentOrDefault(environment, "S3_REGION", "us-east-1"),
+		S3AccessKey: environmentOrDefault(environment, "S3_ACCESS_KEY", ""),
+		S3SecretKey: environmentOrDefault(environment, "S3_SECRET_KEY", ""),
+	}
+
+	flags.StringVar(&config.Kind, "photo-storage", config.Kind, "Photo storage: sqlite, filesystem or s3")
+	flags.StringVar(&config.Dir, "photo-dir", config.Dir, "Directory for photo storage filesystem")
+	flags.StringVar(&config.S3Endpoint, "s3-endpoint", config.S3Endpoint, "S3 endpoint URL")
+	flags.StringVar(&config.S3Bucket, "s3-bucket", config.S3Bucket, "S3 bucket")
+	flags.StringVar(&config.S3Region, "s3-region", config.S3Region, "S3 region")
+	flags.StringVar(&config.S3AccessKey, "s3-access-key", config.S3AccessKey, "S3 access key")
+	flags.StringVar(&config.S3SecretKey, "s3-secret-key", config.S3SecretKey, "S3 secret key")
+
+	return config
+}
+
+// Storage erzeugt einen Speicher der angegebenen Art mit den Parametern der Konfiguration.
+func (c storageConfig) Storage(kind string, tm sqlx.TransactionManager) (photo.BlobStorage, error) {
+	switch kind {
+	case "sqlite":
+		return photo.SqliteStorage{Tm: tm}, nil
+	case "filesystem":
+		return photo.FilesystemStorage{Dir: c.Dir}, nil
+	case "s3":
+		if c.S3Endpoint == "" {
+			return nil, errors.New("s3 photo storage requires --s3-endpoint")
+		}
+		return photo.S3Storage{
+			Endpoint:  c.S3Endpoint,
+			Bucket:    c.S3Bucket,
+			Region:    c.S3Region,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+			Clock:     system.RealClock{},
+			Client:    http.DefaultClient,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown photo storage %q", kind)
+	}
+}
+
+// runPhotos führt die Unterbefehle zur Verwaltung der Photos aus.
+func runPhotos(ctx context.Context, stdout io.Writer, args []string, environment func(string) (string, bool)) error {
+	if len(args) == 0 {
+		return errors.New("usage: dinge photos migrate-storage [flags]")
+	}
+
+	switch args[0] {
+	case "migrate-storage":
+		return migrateStorage(ctx, stdout, args, environment)
+	default:
+		return fmt.Errorf("unknown command photos %v", args[0])
+	}
+}
+
+// migrateStorage verschiebt die Inhalte aller Photos von einem Speicher in einen anderen.
+func migrateStorage(ctx context.Context, stdout io.Writer, args []string, environment func(string) (string, bool)) error {
+	flags := flag.NewFlagSet("migrate-storage", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+
+	datasource := environmentOrDefault(environment, "DB_FILENAME", "dinge.db")
+	flags.StringVar(&datasource, "db-filename", datasource, "Database filename")
+
+	config := newStorageConfig(flags, environment)
+
+	from := "sqlite"
+	flags.StringVar(&from, "from", from, "Source photo storage: sqlite, filesystem or s3")
+
+	to := ""
+	flags.StringVar(&to, "to", to, "Destination photo storage: sqlite, filesystem or s3 (default --photo-storage)")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if to == "" {
+		to = config.Kind
+	}
+
+	if from == to {
+		return fmt.Errorf("source and destination storage are both %v", from)
+	}
+
+	dsn := sqlx.ConnectionString(datasource, sqlx.JOURNAL_WAL, sqlx.FK_ENABLED)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		return err
+	}
+
+	source, err := config.Storage(from, tm)
+	if err != nil {
+		return err
+	}
+
+	destination, err := config.Storage(to, tm)
+	if err != nil {
+		return err
+	}
+
+	repository := photo.Repository{Clock: system.RealClock{}, Tm: tm, Storage: source}
+	moved, err := repository.MigrateStorage(ctx, source, destination)
+	fmt.Fprintf(stdout, "%v photos moved from %v to %v\n", moved, from, to)
+
+	return err
+}
+
+// collectPhotoGarbage entfernt regelmäßig nicht mehr benötigte Photos und ergänzt fehlende Wahrnehmungshashes, bis ctx beendet wird.
+func collectPhotoGarbage(ctx context.Context, logger *slog.Logger, repository *photo.Repository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := repository.CollectGarbage(ctx)
+		if err != nil {
+			logger.Error("collecting photo garbage", slog.String("source", err.Error()))
+		} else if removed > 0 {
+			logger.Info("removed unused photos", slog.Int("count", removed))
+		}
+
+		filled, err := repository.BackfillPerceptualHashes(webx.WithLogger(ctx, logger))
+		if err != nil {
+			logger.Error("computing perceptual hashes", slog.String("source", err.Error()))
+		} else if filled > 0 {
+			logger.Info("computed perceptual hashes", slog.Int("count", filled))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/haschi/dinge/metrics"
 	"github.com/haschi/dinge/webx"
 )
 
@@ -15,26 +16,76 @@ func compose(m1, m2 webx.MiddlewareFunc) webx.MiddlewareFunc {
 	})
 }
 
-func routes(logger *slog.Logger, staticHandler http.Handler, aboutHandler webx.Module, dinge webx.Module, photos webx.Module) *http.ServeMux {
+// routesConfig enthält die Middleware, Module und Handler, aus denen [routes] die Routen des Servers zusammensetzt.
+type routesConfig struct {
+	Logger *slog.Logger
+
+	Headers       webx.SecurityHeaders
+	ErrorPages    webx.ErrorPages
+	CSRF          webx.CSRF
+	Authenticator webx.Authenticator
+
+	// HTTPMetrics erfasst die Anfragen an alle Routen. Metrics gibt die Metriken unter /metrics aus.
+	HTTPMetrics *metrics.HTTP
+	Metrics     http.Handler
+
+	Users    webx.Module
+	Tokens   webx.Module
+	About    webx.Module
+	Dinge    webx.Module
+	Photos   webx.Module
+	Barcodes webx.Module
+
+	Static        http.Handler
+	PhotoDownload http.Handler
+	Liveness      http.Handler
+	Readiness     http.Handler
+}
+
+func routes(config routesConfig) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// middleware
-	requestLogger := webx.LogRequest(logger)
-	nostore := webx.NoStore(logger)
-	defaultMiddleware := webx.MiddlewareFunc(compose(requestLogger, nostore))
+	requestID := webx.RequestID(config.Logger)
+	requestLogger := webx.LogRequest(config.Logger)
+	nostore := webx.NoStore(config.Logger)
 
-	mux.Handle("GET /static/", webx.Combine(staticHandler, requestLogger))
+	// Alle Antworten enthalten die Id der Anfrage und die Security Header, auch statische Inhalte und Umleitungen. Fehler und Panics beantwortet eine Fehlerseite. Die Metriken erfassen alle Anfragen.
+	secure := webx.MiddlewareFunc(compose(compose(compose(requestID, requestLogger), compose(config.HTTPMetrics.Apply, config.Headers.Apply)), config.ErrorPages.Apply))
+
+	// Alle Formulare sind vor Cross-Site Request Forgery geschützt, auch die Anmeldung.
+	publicMiddleware := webx.MiddlewareFunc(compose(compose(secure, nostore), config.CSRF.Apply))
+
+	// Alle Module außer der Anmeldung sind nur für angemeldete Benutzer erreichbar.
+	authentication := webx.RequireAuthentication(config.Authenticator, "/login")
+	defaultMiddleware := webx.MiddlewareFunc(compose(publicMiddleware, authentication))
+
+	mux.Handle("GET /static/", webx.Combine(config.Static, secure))
 
 	// Es gibt noch kein favicon. Daher NotFound
-	mux.Handle("GET /favicon.ico", webx.Combine(http.NotFoundHandler(), requestLogger))
+	mux.Handle("GET /favicon.ico", webx.Combine(http.NotFoundHandler(), secure))
 
 	mux.Handle("GET /{$}", webx.Combine(
 		http.RedirectHandler("/dinge/", http.StatusPermanentRedirect),
-		requestLogger))
+		secure))
+
+	config.Users.Mount(mux, "", publicMiddleware)
+	config.Tokens.Mount(mux, "/tokens", defaultMiddleware)
+	config.Dinge.Mount(mux, "/dinge", defaultMiddleware)
+	config.About.Mount(mux, "/about", defaultMiddleware)
+	config.Photos.Mount(mux, "/dinge/{id}", defaultMiddleware)
+	config.Barcodes.Mount(mux, "/barcode", defaultMiddleware)
+
+	// Photos dürfen vom Browser zwischengespeichert werden, sind aber ebenfalls geschützt.
+	mux.Handle("GET /photos/{id}", webx.Combine(config.PhotoDownload, secure, authentication))
+
+	// Reverse Proxy und Monitoring fragen ohne Anmeldung und häufig an. Die Anfragen werden daher nicht protokolliert.
+	probes := webx.MiddlewareFunc(compose(compose(requestID, config.HTTPMetrics.Apply), config.Headers.Apply))
+	mux.Handle("GET /healthz", webx.Combine(config.Liveness, probes))
+	mux.Handle("GET /readyz", webx.Combine(config.Readiness, probes))
 
-	dinge.Mount(mux, "/dinge", defaultMiddleware)
-	aboutHandler.Mount(mux, "/about", defaultMiddleware)
-	photos.Mount(mux, "/dinge/{id}", defaultMiddleware)
+	// Prometheus ruft die Metriken mit einem API Token ab.
+	mux.Handle("GET /metrics", webx.Combine(config.Metrics, webx.Authorize(webx.RoleViewer, secure, nostore, authentication)...))
 
 	return mux
 }
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/haschi/dinge/about"
+	"github.com/haschi/dinge/barcode"
+	"github.com/haschi/dinge/ding"
+	"github.com/haschi/dinge/metrics"
+	"github.com/haschi/dinge/photo"
+	"github.com/haschi/dinge/templates"
+	"github.com/haschi/dinge/user"
+	"github.com/haschi/dinge/webx"
+)
+
+// newTestRoutes liefert die Routen aller Module. Die Module haben keine Datenbank, und niemand ist angemeldet. Daher erreichen nur die Anmeldung und statische Inhalte ihren Handler.
+//
+// Ein [http.ServeMux] bricht mit panic ab, wenn sich zwei Muster widersprechen. Daher stellt bereits der Aufruf sicher, dass die Routen aller Module zusammenpassen.
+//
+// httpMetrics erfasst die Anfragen an alle Routen. Die Route /metrics gibt sie aus.
+func newTestRoutes(httpMetrics *metrics.HTTP) *http.ServeMux {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	photos := &photo.Module{Templates: templates.TemplatesFileSystem}
+
+	return routes(routesConfig{
+		Logger:        logger,
+		Headers:       webx.DefaultSecurityHeaders,
+		ErrorPages:    webx.ErrorPages{Templates: templates.TemplatesFileSystem, Logger: logger},
+		CSRF:          webx.CSRF{},
+		Authenticator: webx.Authenticators{},
+		HTTPMetrics:   httpMetrics,
+		Metrics:       metrics.Handler{Collectors: []metrics.Collector{httpMetrics}},
+		Users:         &user.Module{Templates: templates.TemplatesFileSystem},
+		Tokens:        &user.TokenModule{Templates: templates.TemplatesFileSystem},
+		About:         &about.Module{Templates: templates.TemplatesFileSystem},
+		Dinge:         &ding.Module{Templates: templates.TemplatesFileSystem},
+		Photos:        photos,
+		Barcodes:      &barcode.Module{},
+		Static:        http.NotFoundHandler(),
+		PhotoDownload: http.HandlerFunc(photos.Download),
+		Liveness:      http.NotFoundHandler(),
+		Readiness:     http.NotFoundHandler(),
+	})
+}
+
+func TestRoutes(t *testing.T) {
+	newTestRoutes(&metrics.HTTP{})
+}
+
+func TestRoutes_SecurityHeaders(t *testing.T) {
+	mux := newTestRoutes(&metrics.HTTP{})
+
+	routes := []string{
+		"GET /",
+		"GET /static/css/main.css",
+		"GET /favicon.ico",
+		"GET /login",
+		"POST /login",
+		"POST /logout",
+		"GET /tokens",
+		"POST /tokens",
+		"POST /tokens/1/revoke",
+		"GET /dinge/",
+		"POST /dinge/",
+		"GET /dinge/new",
+		"GET /dinge/duplicates",
+		"GET /dinge/trash",
+		"GET /dinge/history",
+		"GET /dinge/completions",
+		"POST /dinge/searches",
+		"POST /dinge/searches/delete",
+		"GET /dinge/1",
+		"POST /dinge/1",
+		"GET /dinge/1/edit",
+		"GET /dinge/1/merge",
+		"POST /dinge/1/merge",
+		"GET /dinge/1/archive",
+		"POST /dinge/1/archive",
+		"POST /dinge/1/restore",
+		"GET /dinge/1/purge",
+		"POST /dinge/1/purge",
+		"GET /dinge/delete",
+		"POST /dinge/delete",
+		"GET /dinge/1/",
+		"POST /dinge/1/",
+		"GET /dinge/1/photo/delete",
+		"POST /dinge/1/photo/delete",
+		"GET /photos/1",
+		"GET /about/license",
+		"GET /about/usage",
+		"POST /barcode/",
+		"GET /metrics",
+		"GET /healthz",
+		"GET /readyz",
+	}
+
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+
+		for _, secure := range []bool{true, false} {
+			request := httptest.NewRequest(method, path, nil)
+			if secure {
+				request.TLS = &tls.ConnectionState{}
+			}
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, request)
+
+			header := recorder.Header()
+			if header.Get("Content-Security-Policy") != webx.DefaultSecurityHeaders.ContentSecurityPolicy {
+				t.Errorf("%v Content-Security-Policy = %q", route, header.Get("Content-Security-Policy"))
+			}
+
+			if header.Get("X-Content-Type-Options") != "nosniff" {
+				t.Errorf("%v X-Content-Type-Options = %q; want nosniff", route, header.Get("X-Content-Type-Options"))
+			}
+
+			if header.Get("Referrer-Policy") != "same-origin" {
+				t.Errorf("%v Referrer-Policy = %q; want same-origin", route, header.Get("Referrer-Policy"))
+			}
+
+			if !strings.Contains(header.Get("Permissions-Policy"), "camera=(self)") {
+				t.Errorf("%v Permissions-Policy = %q; want camera=(self)", route, header.Get("Permissions-Policy"))
+			}
+
+			if hsts := header.Get("Strict-Transport-Security"); (hsts != "") != secure {
+				t.Errorf("%v over TLS %v Strict-Transport-Security = %q", route, secure, hsts)
+			}
+		}
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	assets, handler, err := newStaticHandler(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashed := assets.Path("css/main.css")
+	if !regexp.MustCompile(`^/static/css/main\.[0-9a-f]{8}\.css$`).MatchString(hashed) {
+		t.Fatalf("Path(css/main.css) = %q", hashed)
+	}
+
+	if path := assets.Path("/static/css/main.css"); path != hashed {
+		t.Errorf("Path(/static/css/main.css) = %q; want %q", path, hashed)
+	}
+
+	if path := assets.Path("unknown.js"); path != "/static/unknown.js" {
+		t.Errorf("Path(unknown.js) = %q; want /static/unknown.js", path)
+	}
+
+	testcases := []struct {
+		name         string
+		path         string
+		status       int
+		cacheControl string
+	}{
+		{name: "with fingerprint", path: hashed, status: http.StatusOK, cacheControl: "public, max-age=31536000, immutable"},
+		{name: "original name", path: "/static/css/main.css", status: http.StatusOK, cacheControl: ""},
+		{name: "unknown fingerprint", path: "/static/css/main.00000000.css", status: http.StatusNotFound, cacheControl: ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if recorder.Code != tc.status {
+				t.Errorf("status = %v; want %v", recorder.Code, tc.status)
+			}
+
+			if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != tc.cacheControl {
+				t.Errorf("Cache-Control = %q; want %q", cacheControl, tc.cacheControl)
+			}
+		})
+	}
+
+	t.Run("templates", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		response := webx.HtmlResponse[any]{TemplateName: "license", StatusCode: http.StatusOK}
+		if err := response.Render(recorder, webx.Templates{FS: templates.TemplatesFileSystem, Assets: assets}); err != nil {
+			t.Fatal(err)
+		}
+
+		body := recorder.Body.String()
+		if !strings.Contains(body, hashed) {
+			t.Errorf("body does not reference %v", hashed)
+		}
+
+		for _, host := range []string{"unpkg.com", "jsdelivr.net"} {
+			if strings.Contains(body, host) {
+				t.Errorf("body references %v", host)
+			}
+		}
+	})
+}
+
+func TestErrorPages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	errorPages := webx.ErrorPages{Templates: templates.TemplatesFileSystem, Logger: logger}
+
+	handlers := map[string]http.HandlerFunc{
+		"panic": func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		},
+		"server error": func(w http.ResponseWriter, r *http.Request) {
+			webx.ServerError(w, errors.New("secret cause"))
+		},
+		"not found": func(w http.ResponseWriter, r *http.Request) {
+			webx.NotFound(w, r, templates.TemplatesFileSystem)
+		},
+		"unprocessable": func(w http.ResponseWriter, r *http.Request) {
+			webx.Unprocessable(w, r, templates.TemplatesFileSystem)
+		},
+	}
+
+	testcases := []struct {
+		handler     string
+		header      http.Header
+		status      int
+		contentType string
+		body        string
+	}{
+		{handler: "panic", status: http.StatusInternalServerError, contentType: "text/html; charset=utf-8", body: "500 Internal Server Error"},
+		{handler: "panic", header: http.Header{"Accept": {"application/json"}}, status: http.StatusInternalServerError, contentType: "application/json", body: `"status":500`},
+		{handler: "server error", status: http.StatusInternalServerError, contentType: "text/html; charset=utf-8", body: "500 Internal Server Error"},
+		{handler: "server error", header: http.Header{"Authorization": {"Bearer token"}}, status: http.StatusInternalServerError, contentType: "application/json", body: `"error":"Internal Server Error"`},
+		{handler: "not found", status: http.StatusNotFound, contentType: "text/html; charset=utf-8", body: "404 Not Found"},
+		{handler: "not found", header: http.Header{"Accept": {"application/json"}}, status: http.StatusNotFound, contentType: "application/json", body: `"status":404`},
+		{handler: "unprocessable", status: http.StatusUnprocessableEntity, contentType: "text/html; charset=utf-8", body: "422 Unprocessable Entity"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.handler, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, values := range tc.header {
+				request.Header[key] = values
+			}
+
+			recorder := httptest.NewRecorder()
+			errorPages.Apply(handlers[tc.handler]).ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.status {
+				t.Errorf("status = %v; want %v", recorder.Code, tc.status)
+			}
+
+			if contentType := recorder.Header().Get("Content-Type"); contentType != tc.contentType {
+				t.Errorf("Content-Type = %q; want %q", contentType, tc.contentType)
+			}
+
+			body := recorder.Body.String()
+			if !strings.Contains(body, tc.body) {
+				t.Errorf("body = %q; want %q", body, tc.body)
+			}
+
+			if strings.Contains(body, "secret cause") || strings.Contains(body, "boom") {
+				t.Errorf("body contains the cause: %q", body)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	mux := newTestRoutes(&metrics.HTTP{})
+
+	testcases := []struct {
+		name     string
+		incoming string
+		want     string
+	}{
+		{name: "generated", incoming: "", want: `^[0-9a-f]{32}$`},
+		{name: "from proxy", incoming: "proxy-1234.abc", want: `^proxy-1234\.abc$`},
+		{name: "invalid characters", incoming: "<script>", want: `^[0-9a-f]{32}$`},
+		{name: "too long", incoming: strings.Repeat("a", 129), want: `^[0-9a-f]{32}$`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/login", nil)
+			if tc.incoming != "" {
+				request.Header.Set(webx.RequestIDHeader, tc.incoming)
+			}
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, request)
+
+			if id := recorder.Header().Get(webx.RequestIDHeader); !regexp.MustCompile(tc.want).MatchString(id) {
+				t.Errorf("%v = %q; want %v", webx.RequestIDHeader, id, tc.want)
+			}
+		})
+	}
+
+	t.Run("error page and log", func(t *testing.T) {
+		var log strings.Builder
+		logger := slog.New(slog.NewTextHandler(&log, nil))
+
+		handler := webx.Combine(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				webx.LoggerFrom(r.Context()).Info("handler")
+				panic("boom")
+			}),
+			webx.RequestID(logger),
+			webx.ErrorPages{Templates: templates.TemplatesFileSystem, Logger: logger})
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(webx.RequestIDHeader, "req-42")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if !strings.Contains(recorder.Body.String(), "req-42") {
+			t.Errorf("error page does not contain the request id")
+		}
+
+		for _, message := range []string{"handler", "panic serving request"} {
+			if !regexp.MustCompile(`msg="?` + message + `"? .*request_id=req-42`).MatchString(log.String()) {
+				t.Errorf("log entry %q without request id:\n%v", message, log.String())
+			}
+		}
+	})
+}
+
+func TestRoutes_Metrics(t *testing.T) {
+	httpMetrics := &metrics.HTTP{}
+	mux := newTestRoutes(httpMetrics)
+
+	for _, path := range []string{"/login", "/login", "/dinge/1", "/dinge/2", "/unknown"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Ohne Anmeldung leitet /metrics zur Anmeldung um.
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusSeeOther {
+		t.Errorf("GET /metrics status = %v; want %v", recorder.Code, http.StatusSeeOther)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler := metrics.Handler{Collectors: []metrics.Collector{httpMetrics}}
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != metrics.ContentType {
+		t.Errorf("Content-Type = %q; want %q", contentType, metrics.ContentType)
+	}
+
+	body := recorder.Body.String()
+	want := []string{
+		`http_requests_total{method="GET",route="/login",code="200"} 2`,
+		`http_requests_total{method="GET",route="/dinge/{id}",code="303"} 2`,
+		`http_requests_total{method="GET",route="/metrics",code="303"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/dinge/{id}"} 2`,
+		`http_request_duration_seconds_bucket{method="GET",route="/login",le="+Inf"} 2`,
+	}
+
+	for _, line := range want {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics do not contain %v:\n%v", line, body)
+		}
+	}
+
+	// Anfragen ohne Route erreichen die Middleware nicht.
+	if strings.Contains(body, "/dinge/1") || strings.Contains(body, "/unknown") {
+		t.Errorf("metrics contain paths instead of route patterns:\n%v", body)
+	}
+}
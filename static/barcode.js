@@ -1,6 +1,81 @@
-import { BarcodeDetector } from "https://fastly.jsdelivr.net/npm/barcode-detector@2/dist/es/pure.min.js";
+// Erkennt Barcodes im Bild der Kamera.
+//
+// Bietet der Browser die Barcode Detection API an, erkennt er die Barcodes selbst. Andernfalls sendet der Detektor alle 500 Millisekunden ein Bild der Kamera an den Server. Skripte von Drittanbietern werden dafür nicht geladen.
+function createDetector(video, newForm) {
+  if ("BarcodeDetector" in window) {
+    const barcodeDetector = new window.BarcodeDetector();
+    return { interval: 0, detect: () => barcodeDetector.detect(video) };
+  }
+
+  console.log("Barcode Detection API nicht verfügbar, der Server erkennt die Barcodes");
+
+  const canvas = document.createElement("canvas");
+  return {
+    interval: 500,
+    detect: async () => {
+      canvas.width = video.videoWidth;
+      canvas.height = video.videoHeight;
+      canvas.getContext("2d").drawImage(video, 0, 0);
+
+      const frame = await new Promise(resolve => canvas.toBlob(resolve, "image/jpeg", 0.9));
+      const data = new FormData();
+      data.append("file", frame, "frame.jpg");
 
+      const response = await fetch("/barcode/", { method: "POST", body: data, headers: csrfHeaders(newForm) });
+      if (!response.ok) {
+        return [];
+      }
 
+      const result = await response.json();
+      return [{ rawValue: result.code, format: result.format }];
+    }
+  };
+}
+
+// Liefert den Header mit dem CSRF Token der Form für Anfragen mit fetch.
+function csrfHeaders(form) {
+  const token = form.querySelector('input[name="csrf_token"]');
+  return token === null ? {} : { "X-CSRF-Token": token.value };
+}
+
+// Sendet das ausgewählte Photo an den Server und übernimmt den erkannten Produktcode.
+function decodeOnServer() {
+  const photoInput = document.getElementById("code-photo");
+  const photoError = document.getElementById("code-photo-error");
+  const codeInput = document.getElementById("code-input");
+  const newForm = document.getElementById("new-form");
+
+  if (photoInput === null || codeInput === null || newForm === null) {
+    console.error("HTML Elements not found");
+    return;
+  }
+
+  photoInput.addEventListener("change", async () => {
+    const file = photoInput.files[0];
+    if (file === undefined) {
+      return;
+    }
+
+    const data = new FormData();
+    data.append("file", file);
+
+    try {
+      const response = await fetch("/barcode/", { method: "POST", body: data, headers: csrfHeaders(newForm) });
+      const result = await response.json();
+      if (!response.ok) {
+        photoError.textContent = result.error;
+        photoError.hidden = false;
+        return;
+      }
+
+      console.log("Barcode", result.code, "Format", result.format);
+      codeInput.value = result.code;
+      newForm.submit();
+    } catch (error) {
+      console.error("Fehler bei der Barcodeerkennung", error);
+    }
+  });
+}
 
 async function scan() {
   console.log("Running barcode.js");
@@ -36,10 +111,7 @@ async function scan() {
     return;
   }
 
-  const barcodeDetector = new BarcodeDetector();
-  const supportedFormats = await BarcodeDetector.getSupportedFormats();
-  console.log("Unterstützte Barcode-Formate", supportedFormats);
-
+  const detector = createDetector(video, newForm);
 
   showVideo();
 
@@ -47,7 +119,7 @@ async function scan() {
 
   async function detectBarcodes() {
     try {
-      const barcodes = await barcodeDetector.detect(video);
+      const barcodes = await detector.detect();
       if (barcodes.length === 1) {
         console.log("Gefundene Barcodes", barcodes);
         const barcode = barcodes[0]
@@ -66,7 +138,9 @@ async function scan() {
       console.error("Fehler bei der Barcodeerkennung", error)
     }
     finally {
-      if (result === null) {
+      if (result === null && detector.interval > 0) {
+        setTimeout(detectBarcodes, detector.interval)
+      } else if (result === null) {
         requestAnimationFrame(detectBarcodes)
       } else {
         console.log("Frame Animation wird nicht fortgesetzt")
@@ -77,4 +151,5 @@ async function scan() {
   detectBarcodes();
 }
 
+decodeOnServer();
 scan();
@@ -0,0 +1,55 @@
+// Vervollständigt Eingabefelder mit dem Attribut data-completion während der Eingabe.
+//
+// Der Wert des Attributs bestimmt, ob der Name oder der Code eines Dings übernommen wird. Die Vorschläge werden in die datalist des Eingabefeldes geschrieben.
+
+// Wartezeit nach dem letzten Tastendruck, bevor Vorschläge angefragt werden.
+const debounceMilliseconds = 200;
+
+function complete(input) {
+  const list = input.list;
+  const field = input.dataset.completion;
+
+  if (list === null || (field !== "name" && field !== "code")) {
+    console.error("Eingabefeld ohne datalist oder unbekanntes Feld", input.id);
+    return;
+  }
+
+  let timer;
+  let controller;
+
+  input.addEventListener("input", () => {
+    clearTimeout(timer);
+    timer = setTimeout(async () => {
+      const q = input.value.trim();
+      if (q === "") {
+        list.replaceChildren();
+        return;
+      }
+
+      // Eine ältere Anfrage wird abgebrochen, damit ihre Antwort keine neueren Vorschläge überschreibt.
+      controller?.abort();
+      controller = new AbortController();
+
+      try {
+        const response = await fetch("/dinge/completions?q=" + encodeURIComponent(q), { signal: controller.signal });
+        if (!response.ok) {
+          return;
+        }
+
+        const completions = await response.json();
+        list.replaceChildren(...completions.map(completion => {
+          const option = document.createElement("option");
+          option.value = completion[field];
+          option.label = field === "code" ? completion.name : completion.code;
+          return option;
+        }));
+      } catch (error) {
+        if (error.name !== "AbortError") {
+          console.error("Fehler beim Laden der Vorschläge", error);
+        }
+      }
+    }, debounceMilliseconds);
+  });
+}
+
+document.querySelectorAll("input[data-completion]").forEach(complete);
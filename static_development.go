@@ -5,14 +5,19 @@ package main
 import (
 	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/haschi/dinge/webx"
 )
 
-func newStaticHandler(logger *slog.Logger) http.Handler {
+// newStaticHandler berechnet die Fingerabdrücke beim Start. Geänderte Dateien werden trotzdem sofort ausgeliefert, weil der Browser wegen NoStore nichts zwischenspeichert.
+func newStaticHandler(logger *slog.Logger) (*webx.Assets, http.Handler, error) {
 	logger.Info("serving static assets from disk")
-	return webx.Combine(
-		http.StripPrefix("/static", http.FileServer(http.Dir("./static/"))),
-		webx.NoStore(logger),
-	)
+
+	assets, err := webx.NewAssets(os.DirFS("static"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return assets, webx.Combine(assets, webx.NoStore(logger)), nil
 }
@@ -4,14 +4,24 @@ package main
 
 import (
 	"embed"
+	"io/fs"
 	"log/slog"
 	"net/http"
+
+	"github.com/haschi/dinge/webx"
 )
 
 //go:embed "static"
 var Static embed.FS
 
-func newStaticHandler(logger *slog.Logger) http.Handler {
+func newStaticHandler(logger *slog.Logger) (*webx.Assets, http.Handler, error) {
 	logger.Info("serving static assets from compiled virtual filesystem")
-	return http.FileServerFS(Static)
+
+	files, err := fs.Sub(Static, "static")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assets, err := webx.NewAssets(files)
+	return assets, assets, err
 }
@@ -0,0 +1,51 @@
+package testx
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// Schema liefert die Tabellen, Spalten, Indizes und Trigger der Datenbank in sortierter Reihenfolge.
+//
+// Mit Schema lässt sich prüfen, ob eine aktualisierte Datenbank dieselbe Struktur hat wie eine neu angelegte.
+func Schema(db *sql.DB) ([]string, error) {
+	query := `
+	SELECT m.type, m.name, p.name, p.type, p."notnull", p.dflt_value
+	FROM sqlite_master m
+	LEFT JOIN pragma_table_info(m.name) p
+	WHERE m.name NOT LIKE 'sqlite_%'
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	schema := []string{}
+	for rows.Next() {
+		var kind, name string
+		var column, columnType, defaultValue sql.NullString
+		var notNull sql.NullBool
+		if err := rows.Scan(&kind, &name, &column, &columnType, &notNull, &defaultValue); err != nil {
+			return nil, err
+		}
+
+		if !column.Valid {
+			schema = append(schema, fmt.Sprintf("%v %v", kind, name))
+			continue
+		}
+
+		schema = append(schema, fmt.Sprintf("%v %v.%v %v notnull=%v default=%v",
+			kind, name, column.String, columnType.String, notNull.Bool, defaultValue.String))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(schema)
+	return schema, nil
+}
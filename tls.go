@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// selfSignedValidity ist die Gültigkeitsdauer eines selbst signierten Zertifikats.
+const selfSignedValidity = 2 * 365 * 24 * time.Hour
+
+// tlsConfig beschreibt, wie der Server TLS anbietet.
+type tlsConfig struct {
+	Disabled bool
+	CertFile string
+	KeyFile  string
+
+	// RedirectAddress ist die Adresse, an der Anfragen über HTTP zu HTTPS umgeleitet werden. Leer, wenn es keine Umleitung gibt.
+	RedirectAddress string
+}
+
+// newTLSConfig registriert die Parameter für TLS.
+func newTLSConfig(flags *flag.FlagSet, environment func(string) (string, bool)) *tlsConfig {
+	config := &tlsConfig{
+		CertFile:        environmentOrDefault(environment, "TLS_CERT", ""),
+		KeyFile:         environmentOrDefault(environment, "TLS_KEY", ""),
+		RedirectAddress: environmentOrDefault(environment, "HTTP_REDIRECT_ADDRESS", ""),
+	}
+
+	flags.StringVar(&config.CertFile, "tls-cert", config.CertFile, "PEM encoded TLS certificate; a self-signed certificate is used if empty")
+	flags.StringVar(&config.KeyFile, "tls-key", config.KeyFile, "PEM encoded private key of the TLS certificate")
+	flags.StringVar(&config.RedirectAddress, "http-redirect-address", config.RedirectAddress, "HTTP network address that redirects to HTTPS, for example 0.0.0.0:8080")
+	flags.BoolVar(&config.Disabled, "no-tls", config.Disabled, "Serve plain HTTP, for example behind a reverse proxy")
+
+	return config
+}
+
+// Certificate lädt das Zertifikat aus CertFile und KeyFile.
+//
+// Ohne CertFile und KeyFile verwendet Certificate ein selbst signiertes Zertifikat aus dem Verzeichnis dir. Das Zertifikat wird erzeugt, wenn es fehlt, abgelaufen ist oder nicht alle Adressen des Rechners im lokalen Netz enthält.
+func (c tlsConfig) Certificate(logger *slog.Logger, dir string) (tls.Certificate, error) {
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return tls.Certificate{}, errors.New("--tls-cert and --tls-key must be given together")
+		}
+		return tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	}
+
+	certFile := filepath.Join(dir, "dinge-cert.pem")
+	keyFile := filepath.Join(dir, "dinge-key.pem")
+	hosts := localHosts()
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil && coversHosts(certificate.Leaf, hosts, time.Now()) {
+		return certificate, nil
+	}
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return tls.Certificate{}, err
+	}
+
+	logger.Info("creating self-signed certificate", slog.String("file", certFile), slog.Any("hosts", hosts))
+
+	certPEM, keyPEM, err := selfSignedCertificate(hosts, time.Now())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// selfSignedCertificate erzeugt ein selbst signiertes Zertifikat für die Namen und IP Adressen hosts.
+//
+// Das Ergebnis sind Zertifikat und privater Schlüssel im PEM Format.
+func selfSignedCertificate(hosts []string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Dinge"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey})
+	return certPEM, keyPEM, nil
+}
+
+// coversHosts ist wahr, wenn das Zertifikat zum Zeitpunkt now gültig ist und alle Namen und IP Adressen hosts enthält.
+func coversHosts(certificate *x509.Certificate, hosts []string, now time.Time) bool {
+	if certificate == nil || now.After(certificate.NotAfter) {
+		return false
+	}
+
+	for _, host := range hosts {
+		if certificate.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// localHosts liefert den Namen des Rechners, localhost und die IP Adressen des Rechners, unter denen Geräte im lokalen Netz den Server erreichen.
+func localHosts() []string {
+	hosts := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "localhost" {
+		hosts = append([]string{hostname}, hosts...)
+	}
+
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return append(hosts, "127.0.0.1", "::1")
+	}
+
+	for _, address := range addresses {
+		network, ok := address.(*net.IPNet)
+		if !ok || network.IP.IsLinkLocalUnicast() || network.IP.IsMulticast() {
+			continue
+		}
+
+		if ip := network.IP.String(); !slices.Contains(hosts, ip) {
+			hosts = append(hosts, ip)
+		}
+	}
+
+	return hosts
+}
+
+// redirectToHTTPS leitet alle Anfragen zum gleichen Pfad auf dem HTTPS Server an der Adresse httpsAddress um.
+func redirectToHTTPS(httpsAddress string) (http.Handler, error) {
+	_, port, err := net.SplitHostPort(httpsAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		target := "https://" + net.JoinHostPort(host, port)
+		if port == "443" {
+			target = "https://" + host
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("%v%v", target, r.URL.RequestURI()), http.StatusPermanentRedirect)
+	}), nil
+}
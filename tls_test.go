@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSelfSignedCertificate(t *testing.T) {
+	now := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+	hosts := []string{"keller", "localhost", "127.0.0.1", "192.168.1.20"}
+
+	certPEM, keyPEM, err := selfSignedCertificate(hosts, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		hosts []string
+		now   time.Time
+		want  bool
+	}{
+		{name: "all hosts", hosts: hosts, now: now, want: true},
+		{name: "new address", hosts: append(hosts, "192.168.1.21"), now: now, want: false},
+		{name: "expired", hosts: hosts, now: now.Add(selfSignedValidity + time.Hour), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coversHosts(certificate.Leaf, tt.hosts, tt.now); got != tt.want {
+				t.Errorf("coversHosts() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		address      string
+		target       string
+		wantLocation string
+	}{
+		{address: "0.0.0.0:8443", target: "http://keller:8080/dinge/?q=gurke", wantLocation: "https://keller:8443/dinge/?q=gurke"},
+		{address: ":443", target: "http://keller/dinge/1", wantLocation: "https://keller/dinge/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			redirect, err := redirectToHTTPS(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			redirect.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if recorder.Code != http.StatusPermanentRedirect {
+				t.Errorf("GET %v = %v; want %v", tt.target, recorder.Code, http.StatusPermanentRedirect)
+			}
+
+			if location := recorder.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("GET %v Location = %v; want %v", tt.target, location, tt.wantLocation)
+			}
+		})
+	}
+}
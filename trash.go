@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/haschi/dinge/ding"
+)
+
+// trashInterval ist der Zeitabstand, in dem der Papierkorb geleert wird.
+const trashInterval = time.Hour
+
+// emptyTrash löscht regelmäßig Dinge endgültig, die länger als retention im Papierkorb liegen, bis ctx beendet wird.
+func emptyTrash(ctx context.Context, logger *slog.Logger, repository *ding.Repository, retention time.Duration) {
+	ticker := time.NewTicker(trashInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := repository.PapierkorbLeeren(ctx, repository.Clock.Now().Add(-retention))
+		if err != nil {
+			logger.Error("emptying trash", slog.String("source", err.Error()))
+		} else if removed > 0 {
+			logger.Info("removed dinge from trash", slog.Int("count", removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
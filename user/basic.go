@@ -0,0 +1,81 @@
+package user
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/haschi/dinge/webx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuthenticator meldet Benutzer mit HTTP Basic Zugangsdaten an, zum Beispiel für Skripte.
+//
+// Die Zugangsdaten stammen aus einer Datei, siehe [ReadBasicCredentials], und nicht aus der Datenbank.
+type BasicAuthenticator struct {
+	credentials map[string]basicCredential
+}
+
+type basicCredential struct {
+	role webx.Role
+	hash []byte
+}
+
+// ReadBasicCredentials liest die Zugangsdaten eines [BasicAuthenticator].
+//
+// Jede Zeile enthält Benutzername, Rolle und bcrypt Hash des Passworts, getrennt durch Doppelpunkte, zum Beispiel:
+//
+//	kinder:viewer:$2a$10$...
+//
+// Leere Zeilen und Zeilen, die mit # beginnen, werden ignoriert. Den Hash eines Passworts erzeugt der Befehl dinge user hash.
+func ReadBasicCredentials(r io.Reader) (BasicAuthenticator, error) {
+	authenticator := BasicAuthenticator{credentials: map[string]basicCredential{}}
+
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 || fields[0] == "" {
+			return authenticator, fmt.Errorf("line %v: expected name:role:hash", number)
+		}
+
+		role, err := webx.ParseRole(fields[1])
+		if err != nil {
+			return authenticator, fmt.Errorf("line %v: %w", number, err)
+		}
+
+		if _, err := bcrypt.Cost([]byte(fields[2])); err != nil {
+			return authenticator, fmt.Errorf("line %v: %w", number, err)
+		}
+
+		authenticator.credentials[fields[0]] = basicCredential{role: role, hash: []byte(fields[2])}
+	}
+
+	return authenticator, scanner.Err()
+}
+
+// Authenticate prüft die HTTP Basic Zugangsdaten der Anfrage.
+func (a BasicAuthenticator) Authenticate(r *http.Request) (webx.Principal, error) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return webx.Principal{}, webx.ErrUnauthenticated
+	}
+
+	credential, known := a.credentials[name]
+	if !known {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return webx.Principal{}, webx.ErrUnauthenticated
+	}
+
+	if err := bcrypt.CompareHashAndPassword(credential.hash, []byte(password)); err != nil {
+		return webx.Principal{}, webx.ErrUnauthenticated
+	}
+
+	return webx.Principal{Name: name, Role: credential.role}, nil
+}
@@ -0,0 +1,71 @@
+package user_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haschi/dinge/user"
+	"github.com/haschi/dinge/webx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicAuthenticator(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("geheim123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := "# Zugänge für Skripte\n\nkinder:viewer:" + string(hash) + "\n"
+	authenticator, err := user.ReadBasicCredentials(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("ReadBasicCredentials() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		basic    bool
+		want     webx.Principal
+		wantErr  error
+	}{
+		{name: "valid", user: "kinder", password: "geheim123", basic: true, want: webx.Principal{Name: "kinder", Role: webx.RoleViewer}},
+		{name: "wrong password", user: "kinder", password: "geheim", basic: true, wantErr: webx.ErrUnauthenticated},
+		{name: "unknown user", user: "gast", password: "geheim123", basic: true, wantErr: webx.ErrUnauthenticated},
+		{name: "no credentials", basic: false, wantErr: webx.ErrUnauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/dinge/", nil)
+			if tt.basic {
+				request.SetBasicAuth(tt.user, tt.password)
+			}
+
+			got, err := authenticator.Authenticate(request)
+			if err != tt.wantErr || got != tt.want {
+				t.Errorf("BasicAuthenticator.Authenticate() = %v, %v; want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadBasicCredentials_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{name: "missing hash", file: "kinder:viewer"},
+		{name: "unknown role", file: "kinder:kind:$2a$10$abcdefghijklmnopqrstuuRBWiPSHC2.jw2NlT0lf2ea2W5V3ke4Ze"},
+		{name: "no bcrypt hash", file: "kinder:viewer:geheim123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := user.ReadBasicCredentials(strings.NewReader(tt.file)); err == nil {
+				t.Errorf("ReadBasicCredentials(%q) error = nil; want error", tt.file)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+CREATE TABLE users(
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(100) NOT NULL,
+  -- bcrypt Hash des Passworts
+  password_hash TEXT NOT NULL,
+  -- viewer, clerk, editor oder admin. Siehe webx.Role
+  role VARCHAR(10) NOT NULL,
+  created DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX idx_users_name ON users(name);
+-- Die Sitzungen angemeldeter Benutzer. Gespeichert wird nur der SHA-256 Hash des Tokens aus dem Cookie.
+CREATE TABLE sessions(
+  token_hash VARCHAR(64) NOT NULL PRIMARY KEY,
+  users_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+  created DATETIME NOT NULL,
+  expires DATETIME NOT NULL
+);
+CREATE INDEX idx_sessions_expires ON sessions(expires);
+-- Persönliche API Tokens für Skripte und Geräte. Gespeichert wird nur der SHA-256 Hash des Tokens.
+CREATE TABLE api_tokens(
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  users_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  token_hash VARCHAR(64) NOT NULL,
+  -- full, stock oder read. Siehe user.Scope
+  scope VARCHAR(10) NOT NULL,
+  created DATETIME NOT NULL,
+  -- NULL, wenn das Token nicht abläuft
+  expires DATETIME
+);
+CREATE UNIQUE INDEX idx_api_tokens_hash ON api_tokens(token_hash);
+CREATE UNIQUE INDEX idx_api_tokens_name ON api_tokens(users_id, name);
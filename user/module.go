@@ -0,0 +1,309 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haschi/dinge/validation"
+	"github.com/haschi/dinge/webx"
+)
+
+// SessionCookie ist der Name des Cookies, das das Token der Sitzung enthält.
+const SessionCookie = "dinge_session"
+
+type Module struct {
+	Repository *Repository
+	Templates  fs.FS
+
+	// InsecureCookies erlaubt das Sitzungscookie auch über unverschlüsselte Verbindungen. Nur für die Entwicklung ohne TLS.
+	InsecureCookies bool
+}
+
+func (m *Module) Mount(mux *http.ServeMux, prefix string, middleware ...webx.Middleware) {
+	mux.Handle(fmt.Sprintf("GET %v/login", prefix), webx.CombineFunc(m.LoginForm, middleware...))
+	mux.Handle(fmt.Sprintf("POST %v/login", prefix), webx.CombineFunc(m.Login, middleware...))
+	mux.Handle(fmt.Sprintf("POST %v/logout", prefix), webx.CombineFunc(m.Logout, middleware...))
+}
+
+type LoginFormData struct {
+	Name string
+	Next string
+}
+
+// LoginForm zeigt die Anmeldung.
+func (m Module) LoginForm(w http.ResponseWriter, r *http.Request) {
+	data := webx.TemplateData[LoginFormData]{
+		FormValues: LoginFormData{Next: safeNext(r.URL.Query().Get("next"))},
+	}
+
+	m.render(w, data, http.StatusOK)
+}
+
+// Login meldet einen Benutzer mit Name und Passwort an und leitet zur Adresse next weiter.
+func (m Module) Login(w http.ResponseWriter, r *http.Request) {
+	form := validation.NewForm(r)
+	defer form.Close()
+
+	var data LoginFormData
+	var password string
+	err := form.Scan(
+		validation.String("name", &data.Name, validation.IsNotBlank, validation.MaxLength(100)),
+		validation.String("password", &password, validation.IsNotBlank),
+		validation.String("next", &data.Next),
+	)
+
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	data.Next = safeNext(data.Next)
+
+	if !form.IsValid() {
+		m.render(w, webx.TemplateData[LoginFormData]{FormValues: data, ValidationErrors: form.ValidationErrors}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	user, err := m.Repository.Verify(r.Context(), data.Name, password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			invalid := validation.ErrorMap{"password": "Benutzername oder Passwort falsch"}
+			m.render(w, webx.TemplateData[LoginFormData]{FormValues: data, ValidationErrors: invalid}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	token, expires, err := m.Repository.CreateSession(r.Context(), user.Id)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	http.SetCookie(w, m.cookie(token, int(expires.Sub(m.Repository.Clock.Now()).Seconds())))
+	webx.SeeOther("%v", data.Next).ServeHTTP(w, r)
+}
+
+// Logout beendet die Sitzung und leitet zur Anmeldung weiter.
+func (m Module) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		if err := m.Repository.DeleteSession(r.Context(), cookie.Value); err != nil {
+			webx.ServerError(w, err)
+			return
+		}
+	}
+
+	http.SetCookie(w, m.cookie("", -1))
+	webx.SeeOther("/login").ServeHTTP(w, r)
+}
+
+// Authenticate liefert den Benutzer der Sitzung, deren Token die Anfrage im Cookie [SessionCookie] enthält.
+func (m Module) Authenticate(r *http.Request) (webx.Principal, error) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return webx.Principal{}, webx.ErrUnauthenticated
+	}
+
+	user, err := m.Repository.SessionUser(r.Context(), cookie.Value)
+	if err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			return webx.Principal{}, webx.ErrUnauthenticated
+		}
+		return webx.Principal{}, err
+	}
+
+	return user.Principal(), nil
+}
+
+// cookie liefert das Sitzungscookie. Ein negatives Alter löscht das Cookie im Browser.
+func (m Module) cookie(token string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   !m.InsecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (m Module) render(w http.ResponseWriter, data webx.TemplateData[LoginFormData], status int) {
+	response := webx.HtmlResponse[LoginFormData]{
+		TemplateName: "login",
+		Data:         data,
+		StatusCode:   status,
+	}
+
+	if err := response.Render(w, m.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
+
+// safeNext liefert die Adresse, zu der nach der Anmeldung weitergeleitet wird.
+//
+// Nur Adressen dieses Servers sind erlaubt, damit die Anmeldung nicht auf fremde Seiten weiterleitet.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/dinge/"
+	}
+	return next
+}
+
+// TokenModule verwaltet die API Tokens des angemeldeten Benutzers.
+//
+// Ein Token wird nur einmal angezeigt, direkt nachdem es erzeugt wurde. Mit einem API Token lassen sich keine weiteren Tokens erzeugen.
+type TokenModule struct {
+	Repository *Repository
+	Templates  fs.FS
+}
+
+func (m *TokenModule) Mount(mux *http.ServeMux, prefix string, middleware ...webx.Middleware) {
+	viewer := webx.Authorize(webx.RoleViewer, middleware...)
+
+	mux.Handle(fmt.Sprintf("GET %v", prefix), webx.CombineFunc(m.Tokens, viewer...))
+	mux.Handle(fmt.Sprintf("POST %v", prefix), webx.CombineFunc(m.Create, viewer...))
+	mux.Handle(fmt.Sprintf("POST %v/{id}/revoke", prefix), webx.CombineFunc(m.Revoke, viewer...))
+}
+
+type TokensFormData struct {
+	Tokens []Token
+	Scopes []string
+
+	// Token ist das eben erzeugte Token mit dem Namen Name.
+	Token   string
+	Name    string
+	Scope   string
+	Expires time.Time
+}
+
+// Tokens zeigt die API Tokens des angemeldeten Benutzers und eine Form, um ein neues Token zu erzeugen.
+func (m TokenModule) Tokens(w http.ResponseWriter, r *http.Request) {
+	principal, ok := m.principal(w, r)
+	if !ok {
+		return
+	}
+
+	m.render(w, r, principal, webx.TemplateData[TokensFormData]{FormValues: TokensFormData{Scope: string(ScopeFull)}}, http.StatusOK)
+}
+
+// Create erzeugt ein API Token für den angemeldeten Benutzer und zeigt es an.
+//
+// Das Token ist bis einschließlich zum Tag expires gültig. Ohne expires läuft es nicht ab.
+func (m TokenModule) Create(w http.ResponseWriter, r *http.Request) {
+	principal, ok := m.principal(w, r)
+	if !ok {
+		return
+	}
+
+	form := validation.NewForm(r)
+	defer form.Close()
+
+	var data TokensFormData
+	err := form.Scan(
+		validation.String("name", &data.Name, validation.IsNotBlank, validation.MaxLength(100)),
+		validation.String("scope", &data.Scope, validation.StringOptions(ScopeOptions...)),
+		validation.Optional("expires", validation.Date("expires", &data.Expires)),
+	)
+
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	if !data.Expires.IsZero() && !data.Expires.After(m.Repository.Clock.Now().AddDate(0, 0, -1)) {
+		form.ValidationErrors["expires"] = "Datum liegt in der Vergangenheit"
+	}
+
+	if !form.IsValid() {
+		m.render(w, r, principal, webx.TemplateData[TokensFormData]{FormValues: data, ValidationErrors: form.ValidationErrors}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	var expires time.Time
+	if !data.Expires.IsZero() {
+		expires = data.Expires.AddDate(0, 0, 1)
+	}
+
+	token, err := m.Repository.CreateToken(r.Context(), principal.Id, data.Name, Scope(data.Scope), expires)
+	if err != nil {
+		if errors.Is(err, ErrDuplicateToken) {
+			invalid := validation.ErrorMap{"name": "Es gibt bereits ein Token mit diesem Namen"}
+			m.render(w, r, principal, webx.TemplateData[TokensFormData]{FormValues: data, ValidationErrors: invalid}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	data = TokensFormData{Token: token, Name: data.Name, Scope: string(ScopeFull)}
+	m.render(w, r, principal, webx.TemplateData[TokensFormData]{FormValues: data}, http.StatusCreated)
+}
+
+// Revoke widerruft das API Token {id} des angemeldeten Benutzers.
+func (m TokenModule) Revoke(w http.ResponseWriter, r *http.Request) {
+	principal, ok := m.principal(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		webx.NotFound(w, r, m.Templates)
+		return
+	}
+
+	if err := m.Repository.RevokeToken(r.Context(), principal.Id, id); err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			webx.NotFound(w, r, m.Templates)
+			return
+		}
+
+		webx.ServerError(w, err)
+		return
+	}
+
+	webx.SeeOther("/tokens").ServeHTTP(w, r)
+}
+
+// principal liefert den angemeldeten Benutzer.
+//
+// Benutzer aus der Datei für HTTP Basic Authentication haben keine Tokens. Anfragen mit einem API Token dürfen keine Tokens verwalten, damit ein beschränktes Token kein unbeschränktes erzeugen kann. Beide erhalten 403 Forbidden.
+func (m TokenModule) principal(w http.ResponseWriter, r *http.Request) (webx.Principal, bool) {
+	principal, ok := webx.PrincipalFrom(r.Context())
+	if !ok || principal.Id == 0 || principal.Client != "" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return principal, false
+	}
+
+	return principal, true
+}
+
+func (m TokenModule) render(w http.ResponseWriter, r *http.Request, principal webx.Principal, data webx.TemplateData[TokensFormData], status int) {
+	tokens, err := m.Repository.Tokens(r.Context(), principal.Id)
+	if err != nil {
+		webx.ServerError(w, err)
+		return
+	}
+
+	data.FormValues.Tokens = tokens
+	data.FormValues.Scopes = ScopeOptions
+
+	response := webx.HtmlResponse[TokensFormData]{
+		TemplateName: "tokens",
+		Data:         data,
+		StatusCode:   status,
+	}
+
+	if err := response.Render(w, m.Templates); err != nil {
+		webx.ServerError(w, err)
+	}
+}
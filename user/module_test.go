@@ -0,0 +1,348 @@
+package user_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+	"github.com/haschi/dinge/templates"
+	"github.com/haschi/dinge/user"
+	"github.com/haschi/dinge/webx"
+)
+
+func newUserTestModule(db *sql.DB) (webx.Module, error) {
+	if err := sqlx.ExecuteScripts(db, user.CreateScript); err != nil {
+		return nil, err
+	}
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		return nil, err
+	}
+
+	repository := &user.Repository{Clock: system.RealClock{}, Tm: tm}
+	if _, err := repository.Add(context.Background(), "admin", "geheim123", webx.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	return &user.Module{Repository: repository, Templates: templates.TemplatesFileSystem}, nil
+}
+
+func TestModule_Login(t *testing.T) {
+	config := webx.TestserverConfig{
+		Database: webx.InMemoryDatabase(),
+		Module:   newUserTestModule,
+	}
+
+	testserver := webx.NewTestserver(t, "", config)
+	defer testserver.Close()
+
+	response := testserver.Get("/login?next=/dinge/1")
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("GET /login = %v; want %v", response.StatusCode, http.StatusOK)
+	}
+
+	tests := []struct {
+		name           string
+		data           url.Values
+		wantStatusCode int
+		wantLocation   string
+	}{
+		{name: "blank password", data: url.Values{"name": {"admin"}}, wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "wrong password", data: url.Values{"name": {"admin"}, "password": {"falsch"}}, wantStatusCode: http.StatusUnprocessableEntity},
+		{
+			name:           "next",
+			data:           url.Values{"name": {"admin"}, "password": {"geheim123"}, "next": {"/dinge/1"}},
+			wantStatusCode: http.StatusSeeOther,
+			wantLocation:   "/dinge/1",
+		},
+		{
+			name:           "foreign next",
+			data:           url.Values{"name": {"admin"}, "password": {"geheim123"}, "next": {"//example.com/"}},
+			wantStatusCode: http.StatusSeeOther,
+			wantLocation:   "/dinge/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := testserver.Post("/login", tt.data)
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.wantStatusCode {
+				t.Fatalf("POST /login = %v; want %v", response.StatusCode, tt.wantStatusCode)
+			}
+
+			if location := response.Header.Get("Location"); location != tt.wantLocation {
+				t.Errorf("POST /login Location = %v; want %v", location, tt.wantLocation)
+			}
+
+			cookies := response.Cookies()
+			if tt.wantStatusCode != http.StatusSeeOther {
+				if len(cookies) != 0 {
+					t.Errorf("POST /login Cookies = %v; want none", cookies)
+				}
+				return
+			}
+
+			if len(cookies) != 1 {
+				t.Fatalf("POST /login Cookies = %v; want session cookie", cookies)
+			}
+
+			cookie := cookies[0]
+			if cookie.Name != user.SessionCookie || cookie.Value == "" || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+				t.Errorf("POST /login Cookie = %v; want HttpOnly, Secure and SameSite=Lax session cookie", cookie)
+			}
+		})
+	}
+}
+
+func TestRequireAuthentication(t *testing.T) {
+	db, err := sqlx.NewTestDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	module, err := newUserTestModule(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := module.(*user.Module)
+	token, _, err := users.Repository.CreateSession(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protected := webx.RequireAuthentication(users, "/login")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := webx.PrincipalFrom(r.Context())
+		if !ok || principal.Name != "admin" {
+			t.Errorf("PrincipalFrom() = %v, %v; want admin", principal, ok)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		wantStatusCode int
+		wantLocation   string
+	}{
+		{name: "session", method: http.MethodGet, token: token, wantStatusCode: http.StatusNoContent},
+		{name: "no session", method: http.MethodGet, wantStatusCode: http.StatusSeeOther, wantLocation: "/login?next=%2Fdinge%2F%3Fq%3Dgurke"},
+		{name: "unknown session", method: http.MethodPost, token: "unknown", wantStatusCode: http.StatusSeeOther, wantLocation: "/login?next=%2Fdinge%2F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, "/dinge/?q=gurke", nil)
+			if tt.token != "" {
+				request.AddCookie(&http.Cookie{Name: user.SessionCookie, Value: tt.token})
+			}
+
+			recorder := httptest.NewRecorder()
+			protected.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatusCode {
+				t.Errorf("%v /dinge/ = %v; want %v", tt.method, recorder.Code, tt.wantStatusCode)
+			}
+
+			if location := recorder.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("%v /dinge/ Location = %v; want %v", tt.method, location, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	db, err := webx.InMemoryDatabase()()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	module, err := newUserTestModule(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := webx.NewCSRFKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csrf := webx.CSRF{Key: key}
+	mux := http.NewServeMux()
+	module.Mount(mux, "", csrf)
+
+	// Das Formular der Anmeldung enthält das Token zum Cookie.
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != webx.CSRFCookie || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("GET /login Cookies = %v; want HttpOnly and Secure CSRF cookie", cookies)
+	}
+
+	secret := cookies[0].Value
+	token := csrf.Token(secret)
+	field := fmt.Sprintf(`<input type="hidden" name="%v" value="%v">`, webx.CSRFField, token)
+	if !strings.Contains(recorder.Body.String(), field) {
+		t.Errorf("GET /login does not contain %v", field)
+	}
+
+	login := url.Values{"name": {"admin"}, "password": {"geheim123"}, "next": {"/dinge/"}}
+
+	tests := []struct {
+		name           string
+		secret         string
+		token          string
+		header         http.Header
+		wantStatusCode int
+	}{
+		{name: "valid token", secret: secret, token: token, wantStatusCode: http.StatusSeeOther},
+		{name: "token in header", secret: secret, header: http.Header{webx.CSRFHeader: {token}}, wantStatusCode: http.StatusSeeOther},
+		{name: "missing token", secret: secret, wantStatusCode: http.StatusForbidden},
+		{name: "missing cookie", token: token, wantStatusCode: http.StatusForbidden},
+		{name: "token of other cookie", secret: secret, token: csrf.Token("other"), wantStatusCode: http.StatusForbidden},
+		{name: "script with credentials", header: http.Header{"Authorization": {"Basic eDp5"}}, wantStatusCode: http.StatusSeeOther},
+		{name: "cross-site with credentials", header: http.Header{"Authorization": {"Basic eDp5"}, "Sec-Fetch-Site": {"cross-site"}}, wantStatusCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := url.Values{}
+			for key, values := range login {
+				data[key] = values
+			}
+
+			if tt.token != "" {
+				data.Set(webx.CSRFField, tt.token)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(data.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for key, values := range tt.header {
+				for _, value := range values {
+					request.Header.Add(key, value)
+				}
+			}
+
+			if tt.secret != "" {
+				request.AddCookie(&http.Cookie{Name: webx.CSRFCookie, Value: tt.secret})
+			}
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatusCode {
+				t.Errorf("POST /login = %v; want %v", recorder.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func newTokenTestModule(db *sql.DB) (webx.Module, error) {
+	module, err := newUserTestModule(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user.TokenModule{Repository: module.(*user.Module).Repository, Templates: templates.TemplatesFileSystem}, nil
+}
+
+func TestTokenModule(t *testing.T) {
+	config := webx.TestserverConfig{
+		Database: webx.InMemoryDatabase(),
+		Module:   newTokenTestModule,
+	}
+
+	testserver := webx.NewTestserver(t, "/tokens", config)
+	defer testserver.Close()
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		data           url.Values
+		wantStatusCode int
+		wantLocation   string
+	}{
+		{name: "list", method: http.MethodGet, path: "/tokens", wantStatusCode: http.StatusOK},
+		{name: "create", method: http.MethodPost, path: "/tokens", data: url.Values{"name": {"Scanner"}, "scope": {"stock"}}, wantStatusCode: http.StatusCreated},
+		{name: "duplicate", method: http.MethodPost, path: "/tokens", data: url.Values{"name": {"Scanner"}, "scope": {"read"}}, wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "unknown scope", method: http.MethodPost, path: "/tokens", data: url.Values{"name": {"Admin"}, "scope": {"admin"}}, wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "expired", method: http.MethodPost, path: "/tokens", data: url.Values{"name": {"Alt"}, "scope": {"read"}, "expires": {"2000-01-01"}}, wantStatusCode: http.StatusUnprocessableEntity},
+		{name: "revoke", method: http.MethodPost, path: "/tokens/1/revoke", wantStatusCode: http.StatusSeeOther, wantLocation: "/tokens"},
+		{name: "revoke again", method: http.MethodPost, path: "/tokens/1/revoke", wantStatusCode: http.StatusNotFound},
+		{name: "revoke malformed id", method: http.MethodPost, path: "/tokens/x/revoke", wantStatusCode: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			var response *http.Response
+			if step.method == http.MethodPost {
+				response = testserver.Post(step.path, step.data)
+			} else {
+				response = testserver.Get(step.path)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != step.wantStatusCode {
+				t.Errorf("%v %v = %v; want %v", step.method, step.path, response.StatusCode, step.wantStatusCode)
+			}
+
+			if location := response.Header.Get("Location"); location != step.wantLocation {
+				t.Errorf("%v %v Location = %v; want %v", step.method, step.path, location, step.wantLocation)
+			}
+
+			// Fehler beantwortet das Modul mit der Fehlerseite.
+			contentType := response.Header.Get("Content-Type")
+			if step.wantStatusCode == http.StatusNotFound && !strings.HasPrefix(contentType, "text/html") {
+				t.Errorf("%v %v Content-Type = %v; want text/html", step.method, step.path, contentType)
+			}
+		})
+	}
+}
+
+func TestTokenModule_Forbidden(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal webx.Principal
+	}{
+		{name: "api token", principal: webx.Principal{Id: 1, Name: "admin", Role: webx.RoleAdmin, Client: "Scanner"}},
+		{name: "basic authentication", principal: webx.Principal{Name: "kinder", Role: webx.RoleViewer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := webx.TestserverConfig{
+				Database:  webx.InMemoryDatabase(),
+				Module:    newTokenTestModule,
+				Principal: tt.principal,
+			}
+
+			testserver := webx.NewTestserver(t, "/tokens", config)
+			defer testserver.Close()
+
+			response := testserver.Post("/tokens", url.Values{"name": {"Neu"}, "scope": {"full"}})
+			response.Body.Close()
+
+			if response.StatusCode != http.StatusForbidden {
+				t.Errorf("POST /tokens = %v; want %v", response.StatusCode, http.StatusForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/webx"
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrNoRecord = errors.New("no record found")
+var ErrInvalidParameter = errors.New("invalid paramater")
+
+// ErrInvalidCredentials zeigt an, dass Benutzername oder Passwort falsch sind.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrDuplicateName zeigt an, dass es bereits einen Benutzer mit dem Namen gibt.
+var ErrDuplicateName = errors.New("duplicate user name")
+
+// MinPasswordLength ist die Mindestlänge eines Passworts.
+const MinPasswordLength = 8
+
+// SessionDuration ist die Dauer, nach der eine Sitzung abläuft.
+const SessionDuration = 30 * 24 * time.Hour
+
+type Repository struct {
+	Clock Clock
+	Tm    sqlx.TransactionManager
+}
+
+// Add legt den Benutzer name mit dem Passwort password und der Rolle role an.
+//
+// Das Passwort wird nur als bcrypt Hash gespeichert. Das Ergebnis ist die Id des neuen Benutzers.
+func (r Repository) Add(ctx context.Context, name string, password string, role webx.Role) (int64, error) {
+	if ctx == nil {
+		return 0, errors.New("no context provided")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("empty user name: %w", ErrInvalidParameter)
+	}
+
+	if !role.IsValid() {
+		return 0, fmt.Errorf("unknown role %v: %w", int(role), ErrInvalidParameter)
+	}
+
+	if len(password) < MinPasswordLength {
+		return 0, fmt.Errorf("password shorter than %v characters: %w", MinPasswordLength, ErrInvalidParameter)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	statement := `
+	INSERT INTO users(name, password_hash, role, created)
+	VALUES(:name, :hash, :role, :created)`
+
+	result, err := tx.ExecContext(statement,
+		sql.Named("name", name),
+		sql.Named("hash", string(hash)),
+		sql.Named("role", role.String()),
+		sql.Named("created", r.Clock.Now()))
+
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("user %q: %w", name, ErrDuplicateName)
+		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Verify prüft das Passwort password des Benutzers name.
+//
+// Ist der Benutzer unbekannt oder das Passwort falsch, liefert Verify [ErrInvalidCredentials].
+func (r Repository) Verify(ctx context.Context, name string, password string) (User, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return User{}, err
+	}
+
+	defer tx.Rollback()
+
+	var user User
+	var role, hash string
+	row := tx.QueryRowContext(`SELECT id, name, role, created, password_hash FROM users WHERE name = :name`,
+		sql.Named("name", strings.TrimSpace(name)))
+
+	if err := row.Scan(&user.Id, &user.Name, &role, &user.Created, &hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Auch für unbekannte Benutzer wird ein Hash verglichen, damit die Antwortzeit nicht verrät, ob es den Benutzer gibt.
+			bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+			return User{}, ErrInvalidCredentials
+		}
+		return User{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return User{}, ErrInvalidCredentials
+	}
+
+	if user.Role, err = webx.ParseRole(role); err != nil {
+		return User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+// dummyHash liefert den bcrypt Hash eines Passworts, das niemand verwendet.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// CreateSession beginnt eine Sitzung für den Benutzer userId.
+//
+// Das Ergebnis ist das Token der Sitzung, das der Browser als Cookie speichert. Die Datenbank enthält nur den Hash des Tokens. Abgelaufene Sitzungen werden dabei entfernt.
+func (r Repository) CreateSession(ctx context.Context, userId int64) (string, time.Time, error) {
+	if ctx == nil {
+		return "", time.Time{}, errors.New("no context provided")
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := r.Clock.Now().UTC()
+	expires := now.Add(SessionDuration)
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(`DELETE FROM sessions WHERE expires <= :now`, sql.Named("now", now)); err != nil {
+		return "", time.Time{}, err
+	}
+
+	statement := `
+	INSERT INTO sessions(token_hash, users_id, created, expires)
+	VALUES(:hash, :user, :created, :expires)`
+
+	_, err = tx.ExecContext(statement,
+		sql.Named("hash", hashToken(token)),
+		sql.Named("user", userId),
+		sql.Named("created", now),
+		sql.Named("expires", expires))
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expires, tx.Commit()
+}
+
+// SessionUser liefert den Benutzer der Sitzung token.
+//
+// Ist die Sitzung unbekannt oder abgelaufen, liefert SessionUser [ErrNoRecord].
+func (r Repository) SessionUser(ctx context.Context, token string) (User, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return User{}, err
+	}
+
+	defer tx.Rollback()
+
+	q := `
+	SELECT users.id, users.name, users.role, users.created
+	FROM sessions
+	INNER JOIN users ON users.id = sessions.users_id
+	WHERE sessions.token_hash = :hash AND sessions.expires > :now`
+
+	var user User
+	var role string
+	row := tx.QueryRowContext(q, sql.Named("hash", hashToken(token)), sql.Named("now", r.Clock.Now().UTC()))
+	if err := row.Scan(&user.Id, &user.Name, &role, &user.Created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNoRecord
+		}
+		return User{}, err
+	}
+
+	if user.Role, err = webx.ParseRole(role); err != nil {
+		return User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+// DeleteSession beendet die Sitzung token.
+func (r Repository) DeleteSession(ctx context.Context, token string) error {
+	if ctx == nil {
+		return errors.New("no context provided")
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(`DELETE FROM sessions WHERE token_hash = :hash`, sql.Named("hash", hashToken(token))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// randomToken liefert ein zufälliges Token für eine Sitzung oder ein API Token.
+func randomToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// hashToken liefert den SHA-256 Hash eines Tokens. Ein Token ist zufällig und lang genug, so dass kein langsamer Hash nötig ist.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,149 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/user"
+	"github.com/haschi/dinge/webx"
+)
+
+type FixedClock struct {
+	Timestamp time.Time
+}
+
+func (c *FixedClock) Now() time.Time {
+	return c.Timestamp
+}
+
+func withRepository(t *testing.T, testFn func(t *testing.T, r user.Repository, clock *FixedClock)) {
+	t.Helper()
+	db, err := sqlx.NewTestDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+	db.SetMaxOpenConns(0)
+
+	if err := sqlx.ExecuteScripts(db, user.CreateScript); err != nil {
+		t.Fatal("can not setup schema", err)
+	}
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &FixedClock{Timestamp: time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)}
+	testFn(t, user.Repository{Clock: clock, Tm: tm}, clock)
+}
+
+func TestRepository_Add(t *testing.T) {
+	withRepository(t, func(t *testing.T, r user.Repository, clock *FixedClock) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name     string
+			user     string
+			password string
+			role     webx.Role
+			wantErr  error
+		}{
+			{name: "valid", user: "admin", password: "geheim123", role: webx.RoleAdmin, wantErr: nil},
+			{name: "duplicate", user: " admin ", password: "geheim123", role: webx.RoleAdmin, wantErr: user.ErrDuplicateName},
+			{name: "blank name", user: " ", password: "geheim123", role: webx.RoleClerk, wantErr: user.ErrInvalidParameter},
+			{name: "short password", user: "kurz", password: "geheim", role: webx.RoleClerk, wantErr: user.ErrInvalidParameter},
+			{name: "unknown role", user: "rolle", password: "geheim123", role: webx.Role(0), wantErr: user.ErrInvalidParameter},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := r.Add(ctx, tt.user, tt.password, tt.role)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Repository.Add(%q) error = %v; want %v", tt.user, err, tt.wantErr)
+				}
+			})
+		}
+	})
+}
+
+func TestRepository_Verify(t *testing.T) {
+	withRepository(t, func(t *testing.T, r user.Repository, clock *FixedClock) {
+		ctx := context.Background()
+
+		id, err := r.Add(ctx, "admin", "geheim123", webx.RoleAdmin)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name     string
+			user     string
+			password string
+			wantErr  error
+		}{
+			{name: "valid", user: "admin", password: "geheim123", wantErr: nil},
+			{name: "wrong password", user: "admin", password: "geheim124", wantErr: user.ErrInvalidCredentials},
+			{name: "unknown user", user: "gast", password: "geheim123", wantErr: user.ErrInvalidCredentials},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := r.Verify(ctx, tt.user, tt.password)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Repository.Verify(%q) error = %v; want %v", tt.user, err, tt.wantErr)
+				}
+
+				if err == nil && (got.Id != id || got.Role != webx.RoleAdmin) {
+					t.Errorf("Repository.Verify(%q) = %v; want id %v", tt.user, got, id)
+				}
+			})
+		}
+	})
+}
+
+func TestRepository_Session(t *testing.T) {
+	withRepository(t, func(t *testing.T, r user.Repository, clock *FixedClock) {
+		ctx := context.Background()
+
+		id, err := r.Add(ctx, "admin", "geheim123", webx.RoleAdmin)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, expires, err := r.CreateSession(ctx, id)
+		if err != nil {
+			t.Fatalf("Repository.CreateSession() error = %v", err)
+		}
+
+		if want := clock.Timestamp.Add(user.SessionDuration); !expires.Equal(want) {
+			t.Errorf("Repository.CreateSession() expires = %v; want %v", expires, want)
+		}
+
+		if got, err := r.SessionUser(ctx, token); err != nil || got.Id != id || got.Role != webx.RoleAdmin {
+			t.Errorf("Repository.SessionUser() = %v, %v; want user %v", got, err, id)
+		}
+
+		if _, err := r.SessionUser(ctx, "unknown"); !errors.Is(err, user.ErrNoRecord) {
+			t.Errorf("Repository.SessionUser() unknown token error = %v; want %v", err, user.ErrNoRecord)
+		}
+
+		clock.Timestamp = expires
+		if _, err := r.SessionUser(ctx, token); !errors.Is(err, user.ErrNoRecord) {
+			t.Errorf("Repository.SessionUser() expired error = %v; want %v", err, user.ErrNoRecord)
+		}
+
+		clock.Timestamp = clock.Timestamp.Add(-time.Hour)
+		if err := r.DeleteSession(ctx, token); err != nil {
+			t.Fatalf("Repository.DeleteSession() error = %v", err)
+		}
+
+		if _, err := r.SessionUser(ctx, token); !errors.Is(err, user.ErrNoRecord) {
+			t.Errorf("Repository.SessionUser() deleted error = %v; want %v", err, user.ErrNoRecord)
+		}
+	})
+}
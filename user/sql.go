@@ -0,0 +1,8 @@
+package user
+
+import (
+	_ "embed"
+)
+
+//go:embed create.sql
+var CreateScript string
@@ -0,0 +1,261 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haschi/dinge/webx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Scope beschränkt, was ein Skript oder Gerät mit einem API Token darf.
+type Scope string
+
+const (
+	// ScopeFull erlaubt alles, was der Benutzer des Tokens darf.
+	ScopeFull Scope = "full"
+
+	// ScopeStock erlaubt nur Dinge zu suchen, anzusehen, einzulagern und zu entnehmen, zum Beispiel für einen Scanner.
+	ScopeStock Scope = "stock"
+
+	// ScopeRead erlaubt nur Dinge zu suchen und anzusehen.
+	ScopeRead Scope = "read"
+)
+
+// ScopeOptions sind die Namen aller Scopes.
+var ScopeOptions = []string{string(ScopeFull), string(ScopeStock), string(ScopeRead)}
+
+// Role liefert die Rolle, die ein Token mit diesem Scope für einen Benutzer mit der Rolle role hat.
+//
+// Ein Token darf nie mehr als sein Benutzer.
+func (s Scope) Role(role webx.Role) webx.Role {
+	switch s {
+	case ScopeRead:
+		return min(role, webx.RoleViewer)
+	case ScopeStock:
+		return min(role, webx.RoleClerk)
+	default:
+		return role
+	}
+}
+
+// IsValid ist wahr, wenn s einer der Scopes [ScopeFull], [ScopeStock] oder [ScopeRead] ist.
+func (s Scope) IsValid() bool {
+	return s == ScopeFull || s == ScopeStock || s == ScopeRead
+}
+
+// ErrDuplicateToken zeigt an, dass der Benutzer bereits ein API Token mit dem Namen hat.
+var ErrDuplicateToken = errors.New("duplicate token name")
+
+// Token ist ein API Token, mit dem sich Skripte und Geräte im Namen eines Benutzers anmelden.
+type Token struct {
+	Id      int64
+	Name    string
+	Scope   Scope
+	Created time.Time
+
+	// Expires ist der Zeitpunkt, ab dem das Token ungültig ist. Bei Null läuft das Token nicht ab.
+	Expires time.Time
+}
+
+// CreateToken erzeugt für den Benutzer userId das API Token name mit dem Scope scope, das bis expires gültig ist.
+//
+// Ist expires Null, läuft das Token nicht ab. Das Ergebnis ist das Token, das der Benutzer nur dieses eine Mal erhält. Die Datenbank enthält nur den Hash des Tokens.
+func (r Repository) CreateToken(ctx context.Context, userId int64, name string, scope Scope, expires time.Time) (string, error) {
+	if ctx == nil {
+		return "", errors.New("no context provided")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("empty token name: %w", ErrInvalidParameter)
+	}
+
+	if !scope.IsValid() {
+		return "", fmt.Errorf("unknown scope %q: %w", scope, ErrInvalidParameter)
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	statement := `
+	INSERT INTO api_tokens(users_id, name, token_hash, scope, created, expires)
+	VALUES(:user, :name, :hash, :scope, :created, :expires)`
+
+	_, err = tx.ExecContext(statement,
+		sql.Named("user", userId),
+		sql.Named("name", name),
+		sql.Named("hash", hashToken(token)),
+		sql.Named("scope", string(scope)),
+		sql.Named("created", r.Clock.Now().UTC()),
+		sql.Named("expires", sql.NullTime{Time: expires.UTC(), Valid: !expires.IsZero()}))
+
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return "", fmt.Errorf("token %q: %w", name, ErrDuplicateToken)
+		}
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return "", fmt.Errorf("user %v: %w", userId, ErrInvalidParameter)
+		}
+		return "", err
+	}
+
+	return token, tx.Commit()
+}
+
+// Tokens liefert die API Tokens des Benutzers userId, sortiert nach ihrem Namen.
+func (r Repository) Tokens(ctx context.Context, userId int64) ([]Token, error) {
+	tokens := []Token{}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return tokens, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(`
+	SELECT id, name, scope, created, expires
+	FROM api_tokens
+	WHERE users_id = :user
+	ORDER BY name`, sql.Named("user", userId))
+
+	if err != nil {
+		return tokens, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var token Token
+		var expires sql.NullTime
+		if err := rows.Scan(&token.Id, &token.Name, &token.Scope, &token.Created, &expires); err != nil {
+			return tokens, err
+		}
+
+		token.Expires = expires.Time
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return tokens, err
+	}
+
+	return tokens, tx.Commit()
+}
+
+// RevokeToken widerruft das API Token id des Benutzers userId. Gibt es das Token nicht, liefert RevokeToken [ErrNoRecord].
+func (r Repository) RevokeToken(ctx context.Context, userId int64, id int64) error {
+	if ctx == nil {
+		return errors.New("no context provided")
+	}
+
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(`DELETE FROM api_tokens WHERE id = :id AND users_id = :user`,
+		sql.Named("id", id),
+		sql.Named("user", userId))
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoRecord
+	}
+
+	return tx.Commit()
+}
+
+// TokenUser liefert den Benutzer und das API Token zum Token token.
+//
+// Ist das Token unbekannt oder abgelaufen, liefert TokenUser [ErrNoRecord].
+func (r Repository) TokenUser(ctx context.Context, token string) (User, Token, error) {
+	tx, err := r.Tm.BeginTx(ctx)
+	if err != nil {
+		return User{}, Token{}, err
+	}
+
+	defer tx.Rollback()
+
+	q := `
+	SELECT users.id, users.name, users.role, users.created,
+	  api_tokens.id, api_tokens.name, api_tokens.scope, api_tokens.created, api_tokens.expires
+	FROM api_tokens
+	INNER JOIN users ON users.id = api_tokens.users_id
+	WHERE api_tokens.token_hash = :hash
+	  AND (api_tokens.expires IS NULL OR api_tokens.expires > :now)`
+
+	var user User
+	var apiToken Token
+	var role string
+	var expires sql.NullTime
+	row := tx.QueryRowContext(q, sql.Named("hash", hashToken(token)), sql.Named("now", r.Clock.Now().UTC()))
+	err = row.Scan(&user.Id, &user.Name, &role, &user.Created,
+		&apiToken.Id, &apiToken.Name, &apiToken.Scope, &apiToken.Created, &expires)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, Token{}, ErrNoRecord
+		}
+		return User{}, Token{}, err
+	}
+
+	if user.Role, err = webx.ParseRole(role); err != nil {
+		return User{}, Token{}, err
+	}
+
+	apiToken.Expires = expires.Time
+	return user, apiToken, tx.Commit()
+}
+
+// TokenAuthenticator meldet Skripte und Geräte mit einem API Token im Header Authorization: Bearer an.
+type TokenAuthenticator struct {
+	Repository *Repository
+}
+
+// Authenticate liefert den Benutzer des API Tokens der Anfrage. Die Rolle ist durch den Scope des Tokens beschränkt.
+func (a TokenAuthenticator) Authenticate(r *http.Request) (webx.Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return webx.Principal{}, webx.ErrUnauthenticated
+	}
+
+	user, apiToken, err := a.Repository.TokenUser(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			return webx.Principal{}, webx.ErrUnauthenticated
+		}
+		return webx.Principal{}, err
+	}
+
+	principal := user.Principal()
+	principal.Role = apiToken.Scope.Role(user.Role)
+	principal.Client = apiToken.Name
+	return principal, nil
+}
@@ -0,0 +1,146 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/haschi/dinge/user"
+	"github.com/haschi/dinge/webx"
+)
+
+func TestRepository_Token(t *testing.T) {
+	withRepository(t, func(t *testing.T, r user.Repository, clock *FixedClock) {
+		ctx := context.Background()
+
+		id, err := r.Add(ctx, "anna", "geheim123", webx.RoleEditor)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name     string
+			token    string
+			scope    user.Scope
+			expires  time.Time
+			wantErr  error
+			wantRole webx.Role
+		}{
+			{name: "full", token: "Tablet", scope: user.ScopeFull, wantRole: webx.RoleEditor},
+			{name: "stock", token: "Scanner", scope: user.ScopeStock, expires: clock.Timestamp.Add(time.Hour), wantRole: webx.RoleClerk},
+			{name: "read", token: "Hausautomation", scope: user.ScopeRead, wantRole: webx.RoleViewer},
+			{name: "duplicate", token: " Scanner ", scope: user.ScopeRead, wantErr: user.ErrDuplicateToken},
+			{name: "blank name", token: " ", scope: user.ScopeRead, wantErr: user.ErrInvalidParameter},
+			{name: "unknown scope", token: "Unbekannt", scope: user.Scope("admin"), wantErr: user.ErrInvalidParameter},
+		}
+
+		authenticator := user.TokenAuthenticator{Repository: &r}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				token, err := r.CreateToken(ctx, id, tt.token, tt.scope, tt.expires)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Repository.CreateToken() error = %v; want %v", err, tt.wantErr)
+				}
+
+				if err != nil {
+					return
+				}
+
+				request := httptest.NewRequest("GET", "/dinge/", nil)
+				request.Header.Set("Authorization", "Bearer "+token)
+				principal, err := authenticator.Authenticate(request)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := webx.Principal{Id: id, Name: "anna", Role: tt.wantRole, Client: tt.token}
+				if principal != want {
+					t.Errorf("TokenAuthenticator.Authenticate() = %v; want %v", principal, want)
+				}
+			})
+		}
+
+		tokens, err := r.Tokens(ctx, id)
+		if err != nil || len(tokens) != 3 || tokens[0].Name != "Hausautomation" || tokens[1].Name != "Scanner" {
+			t.Fatalf("Repository.Tokens() = %v, %v; want 3 tokens sorted by name", tokens, err)
+		}
+
+		if !tokens[1].Expires.Equal(clock.Timestamp.Add(time.Hour)) || !tokens[0].Expires.IsZero() {
+			t.Errorf("Repository.Tokens() expires = %v, %v", tokens[1].Expires, tokens[0].Expires)
+		}
+
+		if err := r.RevokeToken(ctx, id+1, tokens[0].Id); !errors.Is(err, user.ErrNoRecord) {
+			t.Errorf("Repository.RevokeToken() of other user error = %v; want %v", err, user.ErrNoRecord)
+		}
+
+		if err := r.RevokeToken(ctx, id, tokens[0].Id); err != nil {
+			t.Errorf("Repository.RevokeToken() error = %v", err)
+		}
+
+		if err := r.RevokeToken(ctx, id, tokens[0].Id); !errors.Is(err, user.ErrNoRecord) {
+			t.Errorf("Repository.RevokeToken() twice error = %v; want %v", err, user.ErrNoRecord)
+		}
+	})
+}
+
+func TestTokenAuthenticator_Invalid(t *testing.T) {
+	withRepository(t, func(t *testing.T, r user.Repository, clock *FixedClock) {
+		ctx := context.Background()
+
+		id, err := r.Add(ctx, "anna", "geheim123", webx.RoleClerk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expired, err := r.CreateToken(ctx, id, "Alt", user.ScopeFull, clock.Timestamp.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		revoked, err := r.CreateToken(ctx, id, "Widerrufen", user.ScopeFull, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tokens, err := r.Tokens(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.RevokeToken(ctx, id, tokens[1].Id); err != nil {
+			t.Fatal(err)
+		}
+
+		clock.Timestamp = clock.Timestamp.Add(2 * time.Hour)
+
+		tests := []struct {
+			name          string
+			authorization string
+		}{
+			{name: "no header", authorization: ""},
+			{name: "basic", authorization: "Basic eDp5"},
+			{name: "empty token", authorization: "Bearer "},
+			{name: "unknown token", authorization: "Bearer unknown"},
+			{name: "expired token", authorization: "Bearer " + expired},
+			{name: "revoked token", authorization: "Bearer " + revoked},
+		}
+
+		authenticator := user.TokenAuthenticator{Repository: &r}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				request := httptest.NewRequest("GET", "/dinge/", nil)
+				if tt.authorization != "" {
+					request.Header.Set("Authorization", tt.authorization)
+				}
+
+				if principal, err := authenticator.Authenticate(request); !errors.Is(err, webx.ErrUnauthenticated) {
+					t.Errorf("TokenAuthenticator.Authenticate() = %v, %v; want %v", principal, err, webx.ErrUnauthenticated)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,20 @@
+package user
+
+import (
+	"time"
+
+	"github.com/haschi/dinge/webx"
+)
+
+// User ist ein Benutzer, der sich anmelden kann.
+type User struct {
+	Id      int64
+	Name    string
+	Role    webx.Role
+	Created time.Time
+}
+
+// Principal liefert den Benutzer als angemeldeten Benutzer einer Anfrage.
+func (u User) Principal() webx.Principal {
+	return webx.Principal{Id: u.Id, Name: u.Name, Role: u.Role}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/haschi/dinge/sqlx"
+	"github.com/haschi/dinge/system"
+	"github.com/haschi/dinge/user"
+	"github.com/haschi/dinge/webx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// runUser führt die Unterbefehle zur Verwaltung der Benutzer aus.
+func runUser(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string, environment func(string) (string, bool)) error {
+	if len(args) == 0 {
+		return errors.New("usage: dinge user add [flags] <name> | dinge user hash")
+	}
+
+	switch args[0] {
+	case "add":
+		return addUser(ctx, stdin, stdout, args, environment)
+	case "hash":
+		return hashPassword(stdin, stdout)
+	default:
+		return fmt.Errorf("unknown command user %v", args[0])
+	}
+}
+
+// addUser legt einen Benutzer an. Das Passwort wird aus der ersten Zeile von stdin gelesen.
+func addUser(ctx context.Context, stdin io.Reader, stdout io.Writer, args []string, environment func(string) (string, bool)) error {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+
+	datasource := environmentOrDefault(environment, "DB_FILENAME", "dinge.db")
+	flags.StringVar(&datasource, "db-filename", datasource, "Database filename")
+
+	roleName := webx.RoleViewer.String()
+	flags.StringVar(&roleName, "role", roleName, "Role of the user: viewer, clerk, editor or admin")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	role, err := webx.ParseRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: dinge user add [flags] <name>")
+	}
+
+	name := flags.Arg(0)
+
+	fmt.Fprintf(stdout, "Passwort für %v: ", name)
+	password, err := readPassword(stdin)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout)
+
+	dsn := sqlx.ConnectionString(datasource, sqlx.JOURNAL_WAL, sqlx.FK_ENABLED)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	tm, err := sqlx.NewSqlTransactionManager(db)
+	if err != nil {
+		return err
+	}
+
+	repository := user.Repository{Clock: system.RealClock{}, Tm: tm}
+	id, err := repository.Add(ctx, name, password, role)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "user %v added with id %v and role %v\n", name, id, role)
+	return nil
+}
+
+// hashPassword gibt den bcrypt Hash des Passworts aus der ersten Zeile von stdin aus.
+//
+// Der Hash wird in der Datei für --basic-auth-file verwendet.
+func hashPassword(stdin io.Reader, stdout io.Writer) error {
+	password, err := readPassword(stdin)
+	if err != nil {
+		return err
+	}
+
+	if len(password) < user.MinPasswordLength {
+		return fmt.Errorf("password shorter than %v characters", user.MinPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, string(hash))
+	return nil
+}
+
+// readPassword liest ein Passwort aus der ersten Zeile von stdin.
+func readPassword(stdin io.Reader) (string, error) {
+	password, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+// readBasicCredentials liest die Zugangsdaten für HTTP Basic Authentication aus der Datei filename.
+func readBasicCredentials(filename string) (user.BasicAuthenticator, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return user.BasicAuthenticator{}, err
+	}
+
+	defer file.Close()
+
+	authenticator, err := user.ReadBasicCredentials(file)
+	if err != nil {
+		return authenticator, fmt.Errorf("%v: %w", filename, err)
+	}
+
+	return authenticator, nil
+}
@@ -7,6 +7,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Form struct {
@@ -91,6 +92,45 @@ func Integer64(key string, value *int64, validators ...ValidationFunc[int64]) Sc
 	}
 }
 
+// Optional liest den Wert key nur, wenn er angegeben ist. Ein fehlender oder leerer Wert ist kein Fehler und lässt die Variable unverändert.
+func Optional(key string, scan ScanFunc) ScanFunc {
+	return func(s url.Values) *ValidationError {
+		if strings.TrimSpace(s.Get(key)) == "" {
+			return nil
+		}
+		return scan(s)
+	}
+}
+
+// DateLayout ist das Format eines Datums, wie es HTML Eingabefelder vom Typ date übermitteln.
+const DateLayout = time.DateOnly
+
+func Date(key string, value *time.Time) ScanFunc {
+	return func(s url.Values) *ValidationError {
+		date, err := time.Parse(DateLayout, strings.TrimSpace(s.Get(key)))
+		if err != nil {
+			return &ValidationError{key: key, message: "Kein Datum"}
+		}
+		*value = date
+		return nil
+	}
+}
+
+// Boolean liest den Wert einer Checkbox. Eine nicht angekreuzte Checkbox wird nicht übermittelt und ergibt false.
+func Boolean(key string, value *bool) ScanFunc {
+	return func(s url.Values) *ValidationError {
+		switch s.Get(key) {
+		case "":
+			*value = false
+		case "on", "true", "1":
+			*value = true
+		default:
+			return &ValidationError{key: key, message: "Kein Wahrheitswert"}
+		}
+		return nil
+	}
+}
+
 func validate[T FieldType](key string, value T, validators []ValidationFunc[T]) *ValidationError {
 	for _, validator := range validators {
 		if err := validator(value); err != nil {
@@ -2,7 +2,11 @@ package validation_test
 
 import (
 	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/haschi/dinge/validation"
 )
@@ -205,3 +209,67 @@ func TestIsNotBlank(t *testing.T) {
 		})
 	}
 }
+
+func TestForm_Scan_Filter(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantUnter  int
+		wantSeit   time.Time
+		wantLeer   bool
+		wantErrors validation.ErrorMap
+	}{
+		{
+			name:       "empty",
+			query:      "",
+			wantErrors: validation.ErrorMap{},
+		},
+		{
+			name:       "all values",
+			query:      "unter=5&seit=2024-11-13&leer=on",
+			wantUnter:  5,
+			wantSeit:   time.Date(2024, 11, 13, 0, 0, 0, 0, time.UTC),
+			wantLeer:   true,
+			wantErrors: validation.ErrorMap{},
+		},
+		{
+			name:  "invalid values",
+			query: "unter=0&seit=13.11.2024&leer=vielleicht",
+			wantErrors: validation.ErrorMap{
+				"unter": validation.ErrNumberTooSmall.Error(),
+				"seit":  "Kein Datum",
+				"leer":  "Kein Wahrheitswert",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			form := validation.NewForm(request)
+
+			var unter int
+			var seit time.Time
+			var leer bool
+			err := form.Scan(
+				validation.Optional("unter", validation.Integer("unter", &unter, validation.Min(1))),
+				validation.Optional("seit", validation.Date("seit", &seit)),
+				validation.Boolean("leer", &leer))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !maps.Equal(form.ValidationErrors, tt.wantErrors) {
+				t.Errorf("ValidationErrors = %v; want %v", form.ValidationErrors, tt.wantErrors)
+			}
+
+			if len(tt.wantErrors) > 0 {
+				return
+			}
+
+			if unter != tt.wantUnter || !seit.Equal(tt.wantSeit) || leer != tt.wantLeer {
+				t.Errorf("Scan() = %v, %v, %v; want %v, %v, %v", unter, seit, leer, tt.wantUnter, tt.wantSeit, tt.wantLeer)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package webx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// AssetPrefix ist der Pfad, unter dem die statischen Inhalte erreichbar sind.
+const AssetPrefix = "/static/"
+
+// Assets liefert statische Inhalte wie Stylesheets und Skripte unter Namen mit Fingerabdruck aus, zum Beispiel css/main.3f2a9c1d.css für css/main.css.
+//
+// Der Fingerabdruck ändert sich mit dem Inhalt der Datei. Daher darf der Browser Dateien mit Fingerabdruck unbegrenzt zwischenspeichern und lädt sie trotzdem neu, sobald sie sich ändern. Die Templates erhalten den Namen mit Fingerabdruck von der Funktion asset, siehe [Templates].
+//
+// Dateien sind auch unter ihrem ursprünglichen Namen erreichbar, zum Beispiel für relative Importe in Skripten.
+type Assets struct {
+	files     fs.FS
+	hashed    map[string]string
+	originals map[string]string
+}
+
+// NewAssets berechnet die Fingerabdrücke aller Dateien in files.
+func NewAssets(files fs.FS) (*Assets, error) {
+	assets := &Assets{
+		files:     files,
+		hashed:    map[string]string{},
+		originals: map[string]string{},
+	}
+
+	err := fs.WalkDir(files, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(content)
+		extension := path.Ext(name)
+		hashed := strings.TrimSuffix(name, extension) + "." + hex.EncodeToString(sum[:4]) + extension
+
+		assets.hashed[name] = hashed
+		assets.originals[hashed] = name
+		return nil
+	})
+
+	return assets, err
+}
+
+// Path liefert die Adresse der Datei name mit Fingerabdruck, zum Beispiel /static/css/main.3f2a9c1d.css für css/main.css oder /static/css/main.css.
+//
+// Für unbekannte Dateien liefert Path die Adresse ohne Fingerabdruck.
+func (a *Assets) Path(name string) string {
+	name = strings.TrimPrefix(name, AssetPrefix)
+	if hashed, ok := a.hashed[name]; ok {
+		return AssetPrefix + hashed
+	}
+	return AssetPrefix + name
+}
+
+// ServeHTTP liefert die Datei zur Adresse der Anfrage aus. Dateien mit Fingerabdruck darf der Browser unbegrenzt zwischenspeichern.
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, AssetPrefix)
+	if original, ok := a.originals[name]; ok {
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		}
+		name = original
+	}
+
+	http.ServeFileFS(w, r, a.files, name)
+}
+
+// Templates sind die Templates der Module zusammen mit den statischen Inhalten, auf die sie verweisen.
+//
+// [HtmlResponse.Render] stellt den Templates damit die Funktion asset bereit, die den Namen einer Datei mit Fingerabdruck liefert, siehe [Assets.Path]. Ohne Assets liefert asset die Adresse ohne Fingerabdruck.
+type Templates struct {
+	fs.FS
+	Assets *Assets
+}
+
+// assetPath liefert die Funktion asset für die Templates templates.
+func assetPath(templates fs.FS) func(string) string {
+	if t, ok := templates.(Templates); ok && t.Assets != nil {
+		return t.Assets.Path
+	}
+
+	return func(name string) string {
+		return AssetPrefix + strings.TrimPrefix(name, AssetPrefix)
+	}
+}
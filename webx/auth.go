@@ -0,0 +1,179 @@
+package webx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Principal ist der angemeldete Benutzer einer Anfrage.
+type Principal struct {
+	Id   int64
+	Name string
+	Role Role
+
+	// Client ist der Name des API Tokens, mit dem sich ein Skript oder Gerät im Namen des Benutzers anmeldet. Bei Anfragen aus dem Browser ist Client leer.
+	Client string
+}
+
+// Actor liefert den Namen, unter dem Änderungen des Benutzers protokolliert werden, zum Beispiel "anna" oder "anna (Scanner)" für das API Token Scanner.
+func (p Principal) Actor() string {
+	if p.Client == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%v (%v)", p.Name, p.Client)
+}
+
+// IsAdmin ist wahr, wenn der Benutzer die Rolle [RoleAdmin] besitzt. Templates blenden damit Aktionen aus, die nur Administratoren ausführen dürfen.
+func (p Principal) IsAdmin() bool {
+	return p.Role >= RoleAdmin
+}
+
+// Role legt fest, was ein Benutzer darf. Jede Rolle darf alles, was die vorhergehenden Rollen dürfen.
+type Role int
+
+const (
+	// RoleViewer darf Dinge suchen und ansehen.
+	RoleViewer Role = iota + 1
+
+	// RoleClerk darf zusätzlich Dinge einlagern und entnehmen.
+	RoleClerk
+
+	// RoleEditor darf zusätzlich Dinge bearbeiten, zusammenführen, in den Papierkorb legen und Photos hochladen.
+	RoleEditor
+
+	// RoleAdmin darf zusätzlich Daten exportieren und den Papierkorb leeren. Benutzer werden mit dem Befehl dinge user verwaltet.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleViewer: "viewer",
+	RoleClerk:  "clerk",
+	RoleEditor: "editor",
+	RoleAdmin:  "admin",
+}
+
+// IsValid ist wahr, wenn r eine der Rollen von [RoleViewer] bis [RoleAdmin] ist.
+func (r Role) IsValid() bool {
+	_, ok := roleNames[r]
+	return ok
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+// ParseRole liefert die Rolle mit dem Namen name, zum Beispiel "viewer".
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if roleName == name {
+			return role, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q", name)
+}
+
+// Authenticator ermittelt den angemeldeten Benutzer einer Anfrage.
+//
+// Ist kein Benutzer angemeldet, liefert Authenticate [ErrUnauthenticated].
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Authenticators fragt die Authenticator der Reihe nach, bis einer den Benutzer der Anfrage kennt.
+type Authenticators []Authenticator
+
+func (a Authenticators) Authenticate(r *http.Request) (Principal, error) {
+	for _, authenticator := range a {
+		principal, err := authenticator.Authenticate(r)
+		if !errors.Is(err, ErrUnauthenticated) {
+			return principal, err
+		}
+	}
+	return Principal{}, ErrUnauthenticated
+}
+
+// ErrUnauthenticated zeigt an, dass die Anfrage keinem angemeldeten Benutzer zugeordnet werden kann.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+type principalKey struct{}
+
+// WithPrincipal liefert einen Kontext, der den angemeldeten Benutzer principal enthält.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom liefert den angemeldeten Benutzer aus dem Kontext einer Anfrage.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// RequireAuthentication ist ein Middleware Handler, der nur Anfragen angemeldeter Benutzer weiterleitet.
+//
+// Der angemeldete Benutzer ist im Kontext der Anfrage mit [PrincipalFrom] verfügbar. Anfragen ohne angemeldeten Benutzer werden zur Anmeldung loginPath umgeleitet. Die ursprüngliche Adresse wird dabei im Parameter next übergeben. Anfragen mit ungültigen Zugangsdaten oder ungültigem Token im Header Authorization werden mit 401 Unauthorized beantwortet.
+func RequireAuthentication(authenticator Authenticator, loginPath string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				if authorization := r.Header.Get("Authorization"); errors.Is(err, ErrUnauthenticated) && authorization != "" {
+					if strings.HasPrefix(authorization, "Bearer ") {
+						w.Header().Set("WWW-Authenticate", `Bearer realm="dinge"`)
+					} else {
+						w.Header().Set("WWW-Authenticate", `Basic realm="dinge", charset="UTF-8"`)
+					}
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+
+				if errors.Is(err, ErrUnauthenticated) {
+					target := r.URL.RequestURI()
+					if r.Method != http.MethodGet {
+						target = r.URL.Path
+					}
+
+					location := loginPath + "?" + url.Values{"next": {target}}.Encode()
+					http.Redirect(w, r, location, http.StatusSeeOther)
+					return
+				}
+
+				ServerError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireRole ist ein Middleware Handler, der nur Anfragen von Benutzern mit mindestens der Rolle role weiterleitet.
+//
+// Alle anderen Anfragen werden mit 403 Forbidden beantwortet. RequireRole erwartet den angemeldeten Benutzer im Kontext der Anfrage, siehe [RequireAuthentication].
+func RequireRole(role Role) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFrom(r.Context())
+			if !ok || principal.Role < role {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Authorize liefert die Middleware middleware, ergänzt um die Prüfung der Rolle role mit [RequireRole].
+//
+// Module verwenden Authorize in Mount, um für jede Route die nötige Rolle festzulegen.
+func Authorize(role Role, middleware ...Middleware) []Middleware {
+	return append(slices.Clone(middleware), RequireRole(role))
+}
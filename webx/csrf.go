@@ -0,0 +1,149 @@
+package webx
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// CSRFField ist der Name des Formularfelds, das das CSRF Token enthält.
+const CSRFField = "csrf_token"
+
+// CSRFHeader ist der Name des Headers, in dem Skripte das CSRF Token senden.
+const CSRFHeader = "X-CSRF-Token"
+
+// CSRFCookie ist der Name des Cookies, das das Geheimnis für das CSRF Token enthält.
+const CSRFCookie = "dinge_csrf"
+
+// CSRF ist ein Middleware Handler, der vor Cross-Site Request Forgery schützt.
+//
+// CSRF verwendet ein signiertes Double-Submit-Cookie: Der Browser erhält ein zufälliges Geheimnis im Cookie [CSRFCookie]. Jede Anfrage, die Daten ändert, muss das mit Key signierte Geheimnis als Token im Formularfeld [CSRFField] oder im Header [CSRFHeader] mitsenden. Eine fremde Seite kennt das Geheimnis nicht und kann daher kein gültiges Token erzeugen. Anfragen ohne gültiges Token werden mit 403 Forbidden beantwortet.
+//
+// Bei multipart/form-data Anfragen wird das Formularfeld nicht gelesen, damit Handler den Inhalt als Stream verarbeiten können. Das Token muss dann im Parameter [CSRFField] der Adresse stehen.
+//
+// Anfragen mit Header Authorization von Skripten sind ausgenommen, weil sie ihre Zugangsdaten nicht automatisch vom Browser erhalten.
+//
+// Templates erhalten das Token mit den Funktionen csrfField und csrfToken, siehe [HtmlResponse.Render].
+type CSRF struct {
+	// Key ist der geheime Schlüssel für die Signatur des Tokens, siehe [NewCSRFKey].
+	Key []byte
+
+	// InsecureCookies erlaubt das Cookie auch über unverschlüsselte Verbindungen. Nur für die Entwicklung ohne TLS.
+	InsecureCookies bool
+}
+
+// NewCSRFKey erzeugt einen zufälligen Schlüssel für [CSRF].
+func NewCSRFKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+func (c CSRF) Apply(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := ""
+		if cookie, err := r.Cookie(CSRFCookie); err == nil && validSecret(cookie.Value) {
+			secret = cookie.Value
+		}
+
+		if !isSafeMethod(r.Method) && !c.exempt(r) {
+			if secret == "" || !hmac.Equal([]byte(c.requestToken(r)), []byte(c.Token(secret))) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+
+		if secret == "" {
+			data := make([]byte, 32)
+			if _, err := rand.Read(data); err != nil {
+				ServerError(w, err)
+				return
+			}
+
+			secret = base64.RawURLEncoding.EncodeToString(data)
+			http.SetCookie(w, &http.Cookie{
+				Name:     CSRFCookie,
+				Value:    secret,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   !c.InsecureCookies,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		next.ServeHTTP(&csrfResponseWriter{ResponseWriter: w, token: c.Token(secret)}, r)
+	})
+}
+
+// Token liefert das CSRF Token zum Geheimnis secret aus dem Cookie [CSRFCookie].
+func (c CSRF) Token(secret string) string {
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write([]byte(secret))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// requestToken liefert das Token, das die Anfrage mitsendet.
+func (c CSRF) requestToken(r *http.Request) string {
+	if token := r.Header.Get(CSRFHeader); token != "" {
+		return token
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.URL.Query().Get(CSRFField)
+	}
+
+	return r.FormValue(CSRFField)
+}
+
+// exempt ist wahr für Anfragen von Skripten, die sich mit dem Header Authorization anmelden.
+//
+// Browser senden zwischengespeicherte HTTP Basic Zugangsdaten auch bei Anfragen fremder Seiten. Diese Anfragen erkennt der Header Sec-Fetch-Site und sind nicht ausgenommen.
+func (c CSRF) exempt(r *http.Request) bool {
+	if r.Header.Get("Authorization") == "" {
+		return false
+	}
+
+	site := r.Header.Get("Sec-Fetch-Site")
+	return site == "" || site == "same-origin" || site == "none"
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func validSecret(secret string) bool {
+	data, err := base64.RawURLEncoding.DecodeString(secret)
+	return err == nil && len(data) == 32
+}
+
+// csrfResponseWriter stellt das CSRF Token der Anfrage für [HtmlResponse.Render] bereit.
+type csrfResponseWriter struct {
+	http.ResponseWriter
+	token string
+}
+
+func (w *csrfResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// csrfToken liefert das CSRF Token, das die Middleware [CSRF] für die Antwort w bereitgestellt hat.
+func csrfToken(w http.ResponseWriter) string {
+	for {
+		switch writer := w.(type) {
+		case *csrfResponseWriter:
+			return writer.token
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return ""
+		}
+	}
+}
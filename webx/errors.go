@@ -0,0 +1,181 @@
+package webx
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// ErrorPageData sind die Daten der Fehlerseite.
+type ErrorPageData struct {
+	StatusCode int    `json:"status"`
+	Title      string `json:"error"`
+	Message    string `json:"message"`
+
+	// RequestID ist die Id der Anfrage, mit der der Betreiber den Fehler im Protokoll findet, siehe [RequestID].
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// errorMessages erklären dem Benutzer die Fehler, für die es eine eigene Fehlerseite gibt.
+var errorMessages = map[int]string{
+	http.StatusNotFound:            "Die angeforderte Seite gibt es nicht.",
+	http.StatusUnprocessableEntity: "Die Anfrage enthält ungültige Angaben.",
+	http.StatusInternalServerError: "Bei der Bearbeitung der Anfrage ist ein Fehler aufgetreten.",
+}
+
+// Error antwortet mit der Fehlerseite für den HTTP Status Code status.
+//
+// Anfragen von Skripten erhalten statt der Fehlerseite ein JSON Objekt, siehe [wantsJSON]. Kann das Template nicht ausgegeben werden, ist die Antwort Text.
+func Error(w http.ResponseWriter, r *http.Request, templates fs.FS, status int) {
+	data := ErrorPageData{
+		StatusCode: status,
+		Title:      http.StatusText(status),
+		Message:    errorMessages[status],
+		RequestID:  RequestIDFrom(r.Context()),
+	}
+
+	if wantsJSON(r) {
+		response := JsonResponse[ErrorPageData]{Data: data, StatusCode: status}
+		if err := response.Render(w); err != nil {
+			errorLogger(w).Error("rendering error response", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	if templates != nil {
+		response := HtmlResponse[ErrorPageData]{
+			TemplateName: "error",
+			Data:         TemplateData[ErrorPageData]{FormValues: data},
+			StatusCode:   status,
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := response.Render(w, templates)
+		if err == nil {
+			return
+		}
+
+		w.Header().Del("Content-Type")
+		errorLogger(w).Error("rendering error page", slog.String("error", err.Error()))
+	}
+
+	http.Error(w, data.Title, status)
+}
+
+// NotFound antwortet mit der Fehlerseite 404 Not Found.
+func NotFound(w http.ResponseWriter, r *http.Request, templates fs.FS) {
+	Error(w, r, templates, http.StatusNotFound)
+}
+
+// Unprocessable antwortet mit der Fehlerseite 422 Unprocessable Entity.
+func Unprocessable(w http.ResponseWriter, r *http.Request, templates fs.FS) {
+	Error(w, r, templates, http.StatusUnprocessableEntity)
+}
+
+// wantsJSON ist wahr für Anfragen von Skripten, die JSON erwarten oder sich mit einem API Token anmelden.
+func wantsJSON(r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		return true
+	}
+
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// ErrorPages ist ein Middleware Handler, der Fehler in Handlern protokolliert und mit einer Fehlerseite beantwortet.
+//
+// ErrorPages fängt Panics ab und protokolliert sie mit dem Stack Trace und der Id der Anfrage. Der Browser erhält die Fehlerseite 500 Internal Server Error statt einer abgebrochenen Verbindung. Nach ErrorPages antwortet auch [ServerError] mit der Fehlerseite.
+type ErrorPages struct {
+	Templates fs.FS
+
+	// Logger protokolliert die Fehler, wenn der Kontext der Anfrage keinen Logger enthält, siehe [LoggerFrom].
+	Logger *slog.Logger
+}
+
+func (e ErrorPages) Apply(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := e.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+
+		writer := &errorResponseWriter{
+			ResponseWriter: w,
+			request:        r,
+			templates:      e.Templates,
+			logger:         contextLogger(r.Context(), logger).With(slog.String("method", r.Method), slog.String("path", r.URL.Path)),
+		}
+
+		defer func() {
+			cause := recover()
+			if cause == nil {
+				return
+			}
+
+			// Der Server bricht die Verbindung bei ErrAbortHandler absichtlich ohne Protokoll ab.
+			if cause == http.ErrAbortHandler {
+				panic(cause)
+			}
+
+			writer.logger.Error("panic serving request",
+				slog.String("error", fmt.Sprint(cause)),
+				slog.String("stack", string(debug.Stack())))
+
+			if !writer.written {
+				Error(writer, r, e.Templates, http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(writer, r)
+	})
+}
+
+// errorResponseWriter stellt die Anfrage und die Templates für [ServerError] bereit.
+type errorResponseWriter struct {
+	http.ResponseWriter
+	request   *http.Request
+	templates fs.FS
+	logger    *slog.Logger
+
+	// written ist wahr, sobald der Header der Antwort gesendet ist.
+	written bool
+}
+
+func (w *errorResponseWriter) WriteHeader(code int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *errorResponseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// errorWriter liefert den errorResponseWriter der Middleware [ErrorPages] für die Antwort w, oder nil ohne ErrorPages.
+func errorWriter(w http.ResponseWriter) *errorResponseWriter {
+	for {
+		switch writer := w.(type) {
+		case *errorResponseWriter:
+			return writer
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// errorLogger liefert den Logger der Middleware [ErrorPages] für die Antwort w.
+func errorLogger(w http.ResponseWriter) *slog.Logger {
+	if writer := errorWriter(w); writer != nil {
+		return writer.logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,45 @@
+package webx
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// ImageFromForm liest ein Bild aus dem ersten Teil einer multipart/form-data Anfrage.
+//
+// Der Teil muss den Namen field haben. Es werden höchstens limit Bytes gelesen. Unterstützt werden die Formate JPEG und PNG.
+func ImageFromForm(r *http.Request, field string, limit int64) (image.Image, error) {
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := reader.NextPart()
+	if err != nil {
+
+		return nil, err
+	}
+
+	defer part.Close()
+
+	formname := part.FormName()
+	if formname != field {
+		return nil, fmt.Errorf("unexpected field in multipart form %v", formname)
+	}
+
+	lr := io.LimitReader(part, limit)
+
+	im, _, err := image.Decode(lr)
+	if err != nil {
+		return nil, err
+	}
+
+	return im, nil
+
+}
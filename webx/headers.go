@@ -0,0 +1,68 @@
+package webx
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SecurityHeaders ist ein Middleware Handler, der Header setzt, mit denen der Browser die Anwendung zusätzlich schützt.
+//
+// Leere Felder setzen den entsprechenden Header nicht. [DefaultSecurityHeaders] enthält die Voreinstellung der Anwendung.
+type SecurityHeaders struct {
+	// ContentSecurityPolicy legt fest, woher der Browser Skripte, Styles, Bilder und andere Inhalte laden darf.
+	ContentSecurityPolicy string
+
+	// ReferrerPolicy legt fest, welche Adresse der Browser beim Aufruf anderer Seiten im Header Referer sendet.
+	ReferrerPolicy string
+
+	// PermissionsPolicy legt fest, welche Funktionen des Browsers wie Kamera oder Mikrofon die Anwendung verwenden darf.
+	PermissionsPolicy string
+
+	// HSTSMaxAge ist die Dauer, für die der Browser die Anwendung nur noch über HTTPS aufruft. Der Header Strict-Transport-Security wird nur bei Anfragen über TLS gesetzt.
+	HSTSMaxAge time.Duration
+}
+
+// DefaultSecurityHeaders erlaubt Skripte und Styles aus static, Photos als blob: Adresse für die Vorschau vor dem Hochladen und die Kamera nur für die Anwendung selbst.
+//
+// Die Anwendung lädt nichts von anderen Servern. Ohne Barcode Detection API erkennt der Server die Barcodes.
+var DefaultSecurityHeaders = SecurityHeaders{
+	ContentSecurityPolicy: "default-src 'self'; " +
+		"script-src 'self'; " +
+		"style-src 'self'; " +
+		"img-src 'self' blob: data:; " +
+		"media-src 'self' blob:; " +
+		"connect-src 'self'; " +
+		"object-src 'none'; " +
+		"base-uri 'self'; " +
+		"form-action 'self'; " +
+		"frame-ancestors 'none'",
+	ReferrerPolicy:    "same-origin",
+	PermissionsPolicy: "camera=(self), microphone=(), geolocation=(), payment=(), usb=()",
+	HSTSMaxAge:        365 * 24 * time.Hour,
+}
+
+func (s SecurityHeaders) Apply(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+
+		if s.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", s.ContentSecurityPolicy)
+		}
+
+		if s.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", s.ReferrerPolicy)
+		}
+
+		if s.PermissionsPolicy != "" {
+			header.Set("Permissions-Policy", s.PermissionsPolicy)
+		}
+
+		if s.HSTSMaxAge > 0 && r.TLS != nil {
+			header.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d", int(s.HSTSMaxAge.Seconds())))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -48,7 +48,13 @@ func (w *responseWriterWrapper) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+func (w *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // LogRequest ist ein Middleware Handler, der HTTP Anfragen protokolliert
+//
+// Nach [RequestID] enthält jeder Eintrag die Id der Anfrage.
 func LogRequest(logger *slog.Logger) MiddlewareFunc {
 
 	handlerFunc := func(next http.HandlerFunc) http.HandlerFunc {
@@ -62,7 +68,7 @@ func LogRequest(logger *slog.Logger) MiddlewareFunc {
 
 			message := fmt.Sprintf("%v %v", r.Method, r.URL.Path)
 			duration := time.Since(start)
-			logger.Info(message,
+			contextLogger(r.Context(), logger).Info(message,
 				slog.Int("status", wrapper.statusCode),
 				slog.Duration("duration", duration),
 			)
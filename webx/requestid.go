@@ -0,0 +1,88 @@
+package webx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// RequestIDHeader ist der Name des Headers, der die Id einer Anfrage enthält.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ist die maximale Länge einer Id, die der Client oder ein Reverse Proxy vorgibt.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+type loggerKey struct{}
+
+// RequestID ist ein Middleware Handler, der jeder Anfrage eine Id gibt.
+//
+// Enthält die Anfrage bereits eine gültige Id im Header [RequestIDHeader], zum Beispiel von einem Reverse Proxy, wird diese verwendet. Die Antwort enthält die Id im gleichen Header. Handler und Repositories erhalten die Id mit [RequestIDFrom] und einen Logger, der jeden Eintrag mit der Id versieht, mit [LoggerFrom].
+func RequestID(logger *slog.Logger) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				data := make([]byte, 16)
+				if _, err := rand.Read(data); err != nil {
+					ServerError(w, err)
+					return
+				}
+				id = hex.EncodeToString(data)
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = WithLogger(ctx, logger.With(slog.String("request_id", id)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFrom liefert die Id der Anfrage aus ihrem Kontext, oder einen leeren String ohne [RequestID].
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithLogger liefert einen Kontext, der den Logger logger enthält.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFrom liefert den Logger aus dem Kontext ctx, siehe [RequestID] und [WithLogger]. Enthält der Kontext keinen Logger, ist das Ergebnis [slog.Default].
+func LoggerFrom(ctx context.Context) *slog.Logger {
+	return contextLogger(ctx, slog.Default())
+}
+
+// contextLogger liefert den Logger aus dem Kontext ctx, oder fallback.
+func contextLogger(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// validRequestID ist wahr, wenn id nicht leer und nicht zu lang ist und nur Buchstaben, Ziffern und die Zeichen - _ . : + / = enthält.
+//
+// Die Id landet unverändert im Protokoll und in Fehlerseiten. Daher sind andere Zeichen nicht erlaubt.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
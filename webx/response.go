@@ -2,6 +2,7 @@ package webx
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"log/slog"
@@ -21,12 +22,18 @@ func SeeOther(format string, args ...any) http.HandlerFunc {
 
 // ServerError erzeugt eine Antwort mit HTTP Status Code 500 Internal Server Error
 //
-// TODO: cause muss ausgegeben werden, aber nicht im Body, sondern im Protokoll.
+// ServerError protokolliert cause, aber gibt den Fehler nicht im Body aus. Nach der Middleware [ErrorPages] ist die Antwort die Fehlerseite, sonst Text.
 func ServerError(w http.ResponseWriter, cause error) {
-	println(cause.Error())
-	slog.Error(cause.Error())
-	status := http.StatusInternalServerError
-	http.Error(w, http.StatusText(status), status)
+	writer := errorWriter(w)
+	if writer == nil {
+		slog.Error(cause.Error())
+		status := http.StatusInternalServerError
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	writer.logger.Error(cause.Error())
+	Error(w, writer.request, writer.templates, http.StatusInternalServerError)
 }
 
 type TemplateData[T any] struct {
@@ -43,13 +50,17 @@ type HtmlResponse[T any] struct {
 	StatusCode   int
 }
 
+// Render gibt das Template TemplateName im Layout aus.
+//
+// Templates erzeugen mit csrfField das versteckte Formularfeld mit dem CSRF Token der Anfrage und mit csrfToken das Token selbst, siehe [CSRF]. Die Funktion asset liefert die Adresse einer statischen Datei mit Fingerabdruck, siehe [Templates].
+//
 // TODO Offen:
 // 1. Ausgabe des Fehlers im Log
 // 2. Doppelten Code vermeiden
 func (h HtmlResponse[T]) Render(w http.ResponseWriter, fs fs.FS) error {
 
 	var buffer bytes.Buffer
-	template, err := getTemplate(fs, h.TemplateName)
+	template, err := getTemplate(fs, h.TemplateName, csrfToken(w))
 	if err != nil {
 		return err
 	}
@@ -65,9 +76,37 @@ func (h HtmlResponse[T]) Render(w http.ResponseWriter, fs fs.FS) error {
 	return nil
 }
 
-func getTemplate(fs fs.FS, name string) (*template.Template, error) {
+// JsonResponse liefert den JSON Inhalt für die Antwort auf eine Anfrage
+type JsonResponse[T any] struct {
+	Data       T
+	StatusCode int
+}
+
+func (j JsonResponse[T]) Render(w http.ResponseWriter) error {
+	var buffer bytes.Buffer
+	if err := json.NewEncoder(&buffer).Encode(j.Data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(j.StatusCode)
+	if _, err := buffer.WriteTo(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getTemplate(fs fs.FS, name string, token string) (*template.Template, error) {
+
+	funcs := template.FuncMap{
+		"csrfToken": func() string { return token },
+		"csrfField": func() string {
+			return fmt.Sprintf(`<input type="hidden" name="%v" value="%v">`, CSRFField, token)
+		},
+		"asset": assetPath(fs),
+	}
 
-	t, err := template.New("").ParseFS(
+	t, err := template.New("").Funcs(funcs).ParseFS(
 		fs,
 		fmt.Sprintf("pages/%v/*.tmpl", name),
 		"common/*.tmpl",
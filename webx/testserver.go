@@ -1,8 +1,12 @@
 package webx
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/base64"
+	"mime/multipart"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
 	"testing"
@@ -28,14 +32,52 @@ func NewTestserver(t *testing.T, prefix string, options TestserverConfig) *Tests
 
 	mod.Mount(mux, prefix, options.Middleware...)
 
+	// Alle Anfragen stammen vom angemeldeten Benutzer der Konfiguration.
+	principal := options.Principal
+	if principal.Role == 0 {
+		principal = Principal{Id: 1, Name: "test", Role: RoleAdmin}
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+
+	// Post und Upload senden das CSRF Token automatisch mit.
+	key, err := NewCSRFKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csrf := CSRF{Key: key}
+
 	testserver := &Testserver{
 		db:     db,
 		t:      t,
 		module: mod,
-		server: httptest.NewTLSServer(mux),
+		server: httptest.NewTLSServer(csrf.Apply(handler)),
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
 	}
 
+	secret, err := NewCSRFKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookie := &http.Cookie{Name: CSRFCookie, Value: base64.RawURLEncoding.EncodeToString(secret)}
+	serverUrl, err := url.Parse(testserver.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar.SetCookies(serverUrl, []*http.Cookie{cookie})
+	testserver.csrfToken = csrf.Token(cookie.Value)
+
 	client := testserver.server.Client()
+	client.Jar = jar
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
 	}
@@ -68,8 +110,42 @@ func (t *Testserver) Post(path string, data url.Values) *http.Response {
 
 	t.t.Helper()
 
-	url := t.server.URL + path
-	resp, err := t.server.Client().PostForm(url, data)
+	form := url.Values{CSRFField: {t.csrfToken}}
+	for key, values := range data {
+		form[key] = values
+	}
+
+	resp, err := t.server.Client().PostForm(t.server.URL+path, form)
+	if err != nil {
+		t.t.Fatal(err)
+	}
+
+	return resp
+}
+
+// Upload sendet content als Datei im Feld field einer multipart/form-data Anfrage an path.
+func (t *Testserver) Upload(path string, field string, filename string, content []byte) *http.Response {
+
+	t.t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile(field, filename)
+	if err != nil {
+		t.t.Fatal(err)
+	}
+
+	if _, err := part.Write(content); err != nil {
+		t.t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.t.Fatal(err)
+	}
+
+	target := t.server.URL + path + "?" + url.Values{CSRFField: {t.csrfToken}}.Encode()
+	resp, err := t.server.Client().Post(target, writer.FormDataContentType(), &body)
 	if err != nil {
 		t.t.Fatal(err)
 	}
@@ -82,12 +158,18 @@ type Testserver struct {
 	server *httptest.Server
 	module Module
 	db     *sql.DB
+
+	// csrfToken ist das CSRF Token, das zum Cookie im Cookie Jar des Clients passt.
+	csrfToken string
 }
 
 type TestserverConfig struct {
 	Database   sqlx.DatabaseConstructor
 	Module     ModuleConstructor
 	Middleware []Middleware
+
+	// Principal ist der angemeldete Benutzer aller Anfragen. Ohne Rolle ist der Benutzer ein Administrator.
+	Principal Principal
 }
 
 func NoOpInit(*sql.DB) error { return nil }